@@ -0,0 +1,75 @@
+/*
+Read the ComicInfo.xml metadata shipped with some comics.
+
+Only the pages description and the manga flag are used:
+  - DoublePage mark the double pages, so we don't have to guess from the aspect ratio
+  - Type allow to skip the deleted pages and the advertisements
+  - Manga give the reading direction
+
+The Image attribute of a page is the index of the image in the sorted list of images.
+*/
+package comicinfo
+
+import (
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type Page struct {
+	Image      int    `xml:"Image,attr"`
+	Type       string `xml:"Type,attr"`
+	DoublePage *bool  `xml:"DoublePage,attr"`
+}
+
+type ComicInfo struct {
+	Manga string `xml:"Manga"`
+	Pages []Page `xml:"Pages>Page"`
+}
+
+// check if the file is a ComicInfo.xml
+func IsComicInfo(name string) bool {
+	return strings.EqualFold(filepath.Base(name), "ComicInfo.xml")
+}
+
+// parse the ComicInfo.xml
+func Parse(r io.Reader) (*ComicInfo, error) {
+	c := &ComicInfo{}
+	if err := xml.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reading direction from the manga flag, ok is false if unknown
+func (c *ComicInfo) RightToLeft() (rtl bool, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	switch c.Manga {
+	case "Yes", "YesAndRightToLeft":
+		return true, true
+	case "No":
+		return false, true
+	}
+	return false, false
+}
+
+// description of the page at index i, nil if missing
+func (c *ComicInfo) Page(i int) *Page {
+	if c == nil {
+		return nil
+	}
+	for k := range c.Pages {
+		if c.Pages[k].Image == i {
+			return &c.Pages[k]
+		}
+	}
+	return nil
+}
+
+// deleted pages and advertisements are skipped
+func (p *Page) Skip() bool {
+	return p != nil && (p.Type == "Deleted" || p.Type == "Advertisement")
+}
@@ -16,10 +16,13 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/celogeek/go-comic-converter/v2/internal/converter/options"
+	"github.com/celogeek/go-comic-converter/v2/internal/jpegscale"
+	"github.com/celogeek/go-comic-converter/v2/internal/webpencode"
 )
 
 type Converter struct {
@@ -29,6 +32,8 @@ type Converter struct {
 	order           []converterOrder
 	isZeroValueErrs []error
 	startAt         time.Time
+	downloadDir     string
+	mobiDir         string
 }
 
 // Create a new parser
@@ -67,6 +72,33 @@ func (c *Converter) LoadConfig() error {
 	return c.Options.LoadConfig()
 }
 
+// Load additional profiles
+func (c *Converter) LoadProfiles() error {
+	return c.Options.LoadProfiles()
+}
+
+// The color profiles keep the colors, unless the grayscale is given with or after the profile:
+// in the same config, embedded settings or command line, or by a quality shortcut.
+//
+// called once the profiles are loaded, the profiles file is only known after the parsing.
+func (c *Converter) ApplyProfile() {
+	profile := c.Options.GetProfile()
+	if profile == nil || !profile.Color {
+		return
+	}
+	c.Cmd.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "grayscale":
+			c.Options.GrayscaleOrigin = options.OriginCommandLine
+		case "profile":
+			c.Options.ProfileOrigin = options.OriginCommandLine
+		}
+	})
+	if c.Options.GrayscaleOrigin < c.Options.ProfileOrigin {
+		c.Options.Grayscale = false
+	}
+}
+
 // Create a new section of config
 func (c *Converter) AddSection(section string) {
 	c.order = append(c.order, converterOrderSection{value: section})
@@ -99,38 +131,117 @@ func (c *Converter) AddBoolParam(p *bool, name string, value bool, usage string)
 // Initialize the parser with all section and parameter.
 func (c *Converter) InitParse() {
 	c.AddSection("Output")
-	c.AddStringParam(&c.Options.Input, "input", "", "Source of comic to convert: directory, cbz, zip, cbr, rar, pdf")
-	c.AddStringParam(&c.Options.Output, "output", "", "Output of the EPUB (directory or EPUB): (default [INPUT].epub)")
+	c.AddStringParam(&c.Options.Input, "input", "", "Source of comic to convert: directory, cbz, zip, cbr, rar, cb7, 7z, cbt, tar, tar.gz, pdf, djvu, or an url to one of these files")
+	c.AddBoolParam(&c.Options.Recursive, "recursive", false, "Convert each archive (cbz, cbr, pdf...) found in the tree of the input directory to its own EPUB, when the tree has no image outside of the archives. The output should be a directory or empty, the sub directories are kept.")
+	c.AddStringParam(&c.Options.Batch, "batch", "", "Text file with one input per line, converted one after the other with the same options. The output should be a directory or empty.")
+	c.AddStringParam(&c.Options.Output, "output", "", "Output of the EPUB (directory, EPUB or CBZ with only the converted images): (default [INPUT].epub)")
+	c.AddStringParam(&c.Options.ArchiveCbz, "archivecbz", "", "Also write the pages straighten and cropped, but not resized, in this cbz to archive them")
 	c.AddStringParam(&c.Options.Author, "author", "GO Comic Converter", "Author of the EPUB")
 	c.AddStringParam(&c.Options.Title, "title", "", "Title of the EPUB")
+	c.AddStringParam(&c.Options.Series, "series", "", "Series of the EPUB, to group the volumes in the library (calibre)")
+	c.AddFloatParam(&c.Options.SeriesIndex, "seriesindex", -1, "Position of the EPUB in the series, like 0, 2 or 2.5, -1 = the volume read from the name")
+	c.AddStringParam(&c.Options.Identifier, "identifier", "", "Identifier of the EPUB, an uuid or an isbn, to recognize the converted versions of the same book: (default random uuid)")
+	c.AddBoolParam(&c.Options.FolderTitle, "foldertitle", c.Options.FolderTitle, "Use the name of the folder of the input as the series, and for the title if not set: the directory itself, or the directory containing the file")
+	c.AddBoolParam(&c.Options.NameMetadata, "namemetadata", c.Options.NameMetadata, "Read the series, volume, chapter, year and author from the name of the input, if the title and the author are not set")
+	c.AddStringParam(&c.Options.NameRegex, "nameregex", c.Options.NameRegex, "Pattern of the name of the input with the named groups: series (mandatory), author, volume, chapter and year")
+	c.AddStringParam(&c.Options.Panels, "panels", "", "Panels description (json) for the region based navigation:\n{\"path/image.jpg\": [[x, y, w, h], ...]} with the box in percent of the page")
+	c.AddIntParam(&c.Options.Sample, "sample", 0, "Convert only N pages evenly spaced across the input, to check the quality quickly. The output and the title are marked as a sample.")
+	c.AddStringParam(&c.Options.KeepPages, "keeppages", "", "Keep only these pages of the sorted input, like 10-50,60,80- (starting at 1, 80- until the end)")
+	c.AddStringParam(&c.Options.Colophon, "colophon", "", "Text file rendered as the last page, for the credits or the source. The lines starting with # are headings.")
+	c.AddStringParam(&c.Options.Order, "order", "", "Order of the pages (text file): one source name per line, relative to the directory or archive input")
 
 	c.AddSection("Config")
 	c.AddStringParam(&c.Options.Profile, "profile", c.Options.Profile, fmt.Sprintf("Profile to use: \n%s", c.Options.AvailableProfiles()))
-	c.AddIntParam(&c.Options.Quality, "quality", c.Options.Quality, "Quality of the image")
+	c.AddStringParam(&c.Options.CustomProfile, "customprofile", c.Options.CustomProfile, "Resolution of your device WxH, like 1264x1680, used instead of the profile")
+	c.AddStringParam(&c.Options.ProfilesFile, "profilesfile", c.Options.ProfilesFile, "Additional profiles (json): [{\"code\": \"X\", \"description\": \"My device\", \"width\": 1000, \"height\": 1400, \"color\": false}]")
+	c.AddIntParam(&c.Options.Quality, "quality", c.Options.Quality, "Quality of the image, between 1 and 100. Ignored with png and lossless webp")
+	c.AddStringParam(&c.Options.Qualities, "qualities", "", "Convert once and write an EPUB for each jpeg quality, like 70,80,90, to compare them. The outputs are named with the quality, and -quality is ignored.")
 	c.AddBoolParam(&c.Options.Grayscale, "grayscale", c.Options.Grayscale, "Grayscale image. Ideal for eInk devices.")
-	c.AddIntParam(&c.Options.GrayscaleMode, "grayscale-mode", c.Options.GrayscaleMode, "Grayscale Mode\n0 = normal\n1 = average\n2 = luminance")
+	c.AddIntParam(&c.Options.GrayscaleMode, "grayscale-mode", c.Options.GrayscaleMode, "Grayscale Mode\n0 = normal\n1 = average\n2 = luminance: Rec. 709 luma, 0.2126 R + 0.7152 G + 0.0722 B, ideal for the colored covers\n3 = colorblind: keep apart the red and green of the same luminance")
 	c.AddBoolParam(&c.Options.Crop, "crop", c.Options.Crop, "Crop images")
 	c.AddIntParam(&c.Options.CropRatioLeft, "crop-ratio-left", c.Options.CropRatioLeft, "Crop ratio left: ratio of pixels allow to be non blank while cutting on the left.")
 	c.AddIntParam(&c.Options.CropRatioUp, "crop-ratio-up", c.Options.CropRatioUp, "Crop ratio up: ratio of pixels allow to be non blank while cutting on the top.")
 	c.AddIntParam(&c.Options.CropRatioRight, "crop-ratio-right", c.Options.CropRatioRight, "Crop ratio right: ratio of pixels allow to be non blank while cutting on the right.")
 	c.AddIntParam(&c.Options.CropRatioBottom, "crop-ratio-bottom", c.Options.CropRatioBottom, "Crop ratio bottom: ratio of pixels allow to be non blank while cutting on the bottom.")
+	c.AddIntParam(&c.Options.CropThreshold, "cropthreshold", c.Options.CropThreshold, "Crop threshold: a pixel is blank when its gray is at least this level, between 0 (black) and 255 (white). Lower it to trim the yellowed gutters, raise it to keep the light borders. Also used to detect the blank images.")
+	c.AddFloatParam(&c.Options.CropMinRatio, "cropminratio", c.Options.CropMinRatio, "Crop min ratio: part of the width and of the height kept at least by the crop, between 0 and 1, 0 = no limit")
+	c.AddIntParam(&c.Options.CropPadding, "croppadding", c.Options.CropPadding, "Crop padding: pixels of the background color added around the cropped page, before the resize, so the panels are not flush against the bezel. In pixels of the source.")
+	c.AddBoolParam(&c.Options.Deskew, "deskew", c.Options.Deskew, "Straighten the pages scanned slightly rotated (up to 5 degrees), before the crop. This is slow.")
+	c.AddBoolParam(&c.Options.Descreen, "descreen", c.Options.Descreen, "Blur the halftone dots of the printed comics before reducing the pages, to avoid the moiré. This is slow, and does nothing with noresize.")
+	c.AddBoolParam(&c.Options.ScaledDecode, "scaleddecode", c.Options.ScaledDecode, "Decode the big jpeg directly at 1/2, 1/4 or 1/8 of their size when they stay bigger than the device, faster and with less memory. Needs a build with libjpeg: go build -tags libjpeg")
+	c.AddBoolParam(&c.Options.CropFromFirst, "cropfromfirst", c.Options.CropFromFirst, "Crop all the pages of the same size with the margins found on the first page (after the cover) instead of looking for each page. Ideal when the pages share the same framing.")
 	c.AddIntParam(&c.Options.Brightness, "brightness", c.Options.Brightness, "Brightness readjustement: between -100 and 100, > 0 lighter, < 0 darker")
 	c.AddIntParam(&c.Options.Contrast, "contrast", c.Options.Contrast, "Contrast readjustement: between -100 and 100, > 0 more contrast, < 0 less contrast")
+	c.AddBoolParam(&c.Options.AutoContrast, "autocontrast", c.Options.AutoContrast, "Stretch the levels of each page, after the resize and the grayscale: the darkest gray become black and the lightest white. Ideal for the faded scans.")
+	c.AddFloatParam(&c.Options.AutoContrastClip, "autocontrast-clip", c.Options.AutoContrastClip, "Percent of the pixels ignored at each end of the levels by the autocontrast, between 0 and 50, to not be stopped by a few dark or light dots")
+	c.AddFloatParam(&c.Options.Gamma, "gamma", c.Options.Gamma, "Gamma correction of the midtones, applied last: between 0.1 and 3, > 1 lighter, < 1 darker. The e-ink screens render the midtones darker than the LCD, 1.2 to 1.5 compensates.")
+	c.AddBoolParam(&c.Options.Sharpen, "sharpen", c.Options.Sharpen, "Sharpen the edges of the pages after the resize (unsharp mask), the text of the high resolution scans get soft once reduced")
+	c.AddFloatParam(&c.Options.SharpenAmount, "sharpen-amount", c.Options.SharpenAmount, "Strength of the sharpen, between 0 and 5")
+	c.AddBoolParam(&c.Options.Dither, "dither", c.Options.Dither, "Reduce the grayscale pages to the levels of gray of the device with the Floyd–Steinberg dithering, to avoid the banding of the gradients. Use it with the png format, the jpeg blurs the pattern.")
+	c.AddIntParam(&c.Options.DitherLevels, "dither-levels", c.Options.DitherLevels, "Levels of gray of the device for the dither, between 2 and 256: 16 for the recent e-ink screens, 4 for the oldest")
 	c.AddBoolParam(&c.Options.AutoRotate, "autorotate", c.Options.AutoRotate, "Auto Rotate page when width > height")
 	c.AddBoolParam(&c.Options.AutoSplitDoublePage, "autosplitdoublepage", c.Options.AutoSplitDoublePage, "Auto Split double page when width > height")
+	c.AddStringParam(&c.Options.SpreadMode, "spreadmode", c.Options.SpreadMode, "Output of the double page when auto split\nboth  = whole page followed by the 2 halves\nsplit = only the 2 halves\nkeep  = only the whole page")
+	c.AddFloatParam(&c.Options.DoublePageRatio, "doublepage-ratio", c.Options.DoublePageRatio, "A page is a double page when its width is greater than its height multiplied by this ratio, for the auto rotate and the auto split\n  1 = wider than high\n1.3 = ignore the pages slightly in landscape")
+	c.AddBoolParam(&c.Options.LongStrip, "longstrip", c.Options.LongStrip, "Concatenate the pages of each chapter vertically into long strips, for the readers with a continuous scroll. The cover stays alone.")
+	c.AddIntParam(&c.Options.LongStripHeight, "longstrip-height", c.Options.LongStripHeight, "Maximum height of a long strip in pixels, a chapter taller than this is splitted into several strips")
 	c.AddBoolParam(&c.Options.NoBlankImage, "noblankimage", c.Options.NoBlankImage, "Remove blank image")
 	c.AddBoolParam(&c.Options.Manga, "manga", c.Options.Manga, "Manga mode (right to left)")
+	c.AddBoolParam(&c.Options.AutoDirection, "autodirection", c.Options.AutoDirection, "Set the manga mode from the ComicInfo.xml of the input, or else from the side of the gutter of the first scanned pages. -manga is used if the direction is unknown or if it is set explicitly.")
 	c.AddBoolParam(&c.Options.HasCover, "hascover", c.Options.HasCover, "Has cover. Indicate if your comic have a cover. The first page will be used as a cover and include after the title.")
+	c.AddBoolParam(&c.Options.NoCoverFile, "nocoverfile", c.Options.NoCoverFile, "Do not use the cover.jpg or folder.jpg of the input as the cover, the first page is used")
+	c.AddStringParam(&c.Options.Cover, "cover", c.Options.Cover, "Image of the input used as the cover, by its page number starting at 1 or by its name, like 5 or chapter1/page05.jpg. The image also stays at its place in the pages. Directories and archives only.")
+	c.AddBoolParam(&c.Options.CoverOnly, "coveronly", c.Options.CoverOnly, "Remove the image selected with -cover from the pages, it is only the cover")
+	c.AddStringParam(&c.Options.CoverFit, "coverfit", c.Options.CoverFit, "Fit of the cover to the device aspect ratio\nfit  = keep the cover as is\nfill = crop the center of the cover\npad  = add the background color around the cover")
+	c.AddStringParam(&c.Options.CoverRatio, "coverratio", c.Options.CoverRatio, "Aspect ratio W:H of the cover instead of the device one, like 16:9 for a store listing. The cover is padded unless -coverfit fill.")
+	c.AddIntParam(&c.Options.CoverBack, "coverback", 0, "Page N to stitch as the back cover with the cover, for a wraparound cover: 0 = disabled, -1 = last page. The page is also kept in the content.")
 	c.AddIntParam(&c.Options.LimitMb, "limitmb", c.Options.LimitMb, "Limit size of the EPUB: Default nolimit (0), Minimum 20")
+	c.AddIntParam(&c.Options.LimitFiles, "limitfiles", c.Options.LimitFiles, "Limit the number of images of the EPUB, cover, title and chapter thumbnails included: Default nolimit (0), Minimum 3")
+	c.AddStringParam(&c.Options.PartFormat, "partformat", c.Options.PartFormat, "Suffix of the output name when the EPUB is split. {part} and {total} are zero padded to the number of parts.")
+	c.AddBoolParam(&c.Options.LimitExcludeCover, "limitexcludecover", c.Options.LimitExcludeCover, "Do not count the cover and the title in the limitmb, so the content is splitted evenly. Each part is bigger than the limit by their size.")
+	c.AddBoolParam(&c.Options.NoZip64, "nozip64", c.Options.NoZip64, "Split the EPUB before reaching 4Gb or 65535 files, for the readers that don't support zip64")
 	c.AddBoolParam(&c.Options.StripFirstDirectoryFromToc, "strip", c.Options.StripFirstDirectoryFromToc, "Strip first directory from the TOC if only 1")
+	c.AddIntParam(&c.Options.MinChapterPages, "minchapterpages", c.Options.MinChapterPages, "Merge in the TOC the chapters with less pages than this into the previous one: 0 = never")
+	c.AddBoolParam(&c.Options.TocThumbnails, "tocthumbnails", c.Options.TocThumbnails, "Add a thumbnail of the first page of each chapter in the TOC, for the readers that display them. This increases the size of the EPUB.")
 	c.AddIntParam(&c.Options.SortPathMode, "sort", c.Options.SortPathMode, "Sort path mode\n0 = alpha for path and file\n1 = alphanum for path and alpha for file\n2 = alphanum for path and file")
+	c.AddBoolParam(&c.Options.ReverseOrder, "reverseorder", false, "Reverse the sorted pages, for the sources numbered backward. This doesn't change the reading direction, see -manga.")
+	c.AddBoolParam(&c.Options.IncludeHidden, "includehidden", c.Options.IncludeHidden, "Include hidden files and directories (starting with a dot)")
+	c.AddBoolParam(&c.Options.WarnUnsupported, "warnunsupported", c.Options.WarnUnsupported, "Report the files of the input skipped because they are not a supported image")
+	c.AddBoolParam(&c.Options.SniffContent, "sniffcontent", c.Options.SniffContent, "Detect the images without extension from their content: jpeg, png, webp, avif, gif or tiff")
+	c.AddStringParam(&c.Options.RarMode, "rarmode", c.Options.RarMode, "Read mode of the rar\nauto   = sequential if the rar is solid\nsolid  = always sequential\nrandom = open each image independently")
+	c.AddBoolParam(&c.Options.CheckArchive, "checkarchive", c.Options.CheckArchive, "Verify the checksum of all the entries of a cbz, cbr or cb7 input before the conversion, to fail fast on a partial download")
+	c.AddStringParam(&c.Options.Password, "password", "", "Password of an encrypted cbz or cbr, it is never saved with the settings")
+	c.AddBoolParam(&c.Options.SkipBad, "skipbad", c.Options.SkipBad, "Skip the unreadable images with a warning instead of stopping the conversion, the next pages are renumbered")
+	c.AddBoolParam(&c.Options.NoEmbeddedSettings, "noembeddedsettings", c.Options.NoEmbeddedSettings, "Ignore the settings embedded in a cbz or cbr input, in a gcc.conf file or in the comment of the cbz")
+	c.AddIntParam(&c.Options.PdfDpi, "pdfdpi", c.Options.PdfDpi, "Reduce the images extracted from a pdf to this resolution (dpi) of the page: 0 = keep the embedded resolution")
+	c.AddStringParam(&c.Options.PdfRender, "pdfrender", c.Options.PdfRender, "Rendering of the pdf pages with pdftoppm (poppler), at the pdfdpi or 300 dpi\nauto   = only the pages without image (vector, text)\nalways = all the pages\nnever  = only extract the images")
+	c.AddIntParam(&c.Options.SamplePages, "sample-pages", c.Options.SamplePages, "Number of pages inspected by the heuristics: the first pages for -cropfromfirst, evenly spaced among the first 48 pages for -autodirection, evenly spaced for the aspect ratio of the source. 0 = default (first page, 16 pages, all pages)")
 	c.AddStringParam(&c.Options.ForegroundColor, "foreground-color", c.Options.ForegroundColor, "Foreground color in hexa format RGB. Black=000, White=FFF")
 	c.AddStringParam(&c.Options.BackgroundColor, "background-color", c.Options.BackgroundColor, "Background color in hexa format RGB. Black=000, White=FFF, Light Gray=DDD, Dark Gray=777")
 	c.AddBoolParam(&c.Options.NoResize, "noresize", c.Options.NoResize, "Do not reduce image size if exceed device size")
-	c.AddStringParam(&c.Options.Format, "format", c.Options.Format, "Format of output images: jpeg (lossy), png (lossless)")
+	c.AddStringParam(&c.Options.ResizeFilter, "resize", c.Options.ResizeFilter, "Interpolation of the resize\nnearest    = fastest, blocky\nbox        = area average\nbilinear   = fast, a bit soft\ncatmullrom = sharp\nlanczos    = sharpest, ideal for the line art")
+	c.AddStringParam(&c.Options.FitMode, "fit", c.Options.FitMode, "Fit of the pages into the device\nfit     = reduce the page, keep its aspect ratio\ncontain = like fit, then pad to the aspect ratio of the device\ncover   = crop the center to the aspect ratio of the device, then reduce\nstretch = resize to the size of the device, distort the page")
+	c.AddStringParam(&c.Options.PadColor, "padcolor", c.Options.PadColor, "Color of the padding of -fit contain: white or black, the background color by default")
+	c.AddFloatParam(&c.Options.BoxRatio, "boxratio", c.Options.BoxRatio, "Use area average (box) instead of the resize filter when the image is reduced at least by this ratio\n0 = never\n4 = reduced by 4 or more, ideal to keep small text readable")
+	c.AddStringParam(&c.Options.Format, "format", c.Options.Format, "Format of output images: jpeg (lossy), png (lossless), webp (lossy or lossless, smaller), kepub (jpeg in a KEPUB, same as -target kobo). webp needs a build with webp: go build -tags webp")
+	c.AddBoolParam(&c.Options.Lossless, "lossless", c.Options.Lossless, "Encode the webp without loss, the quality is then ignored")
+	c.AddBoolParam(&c.Options.LosslessCover, "losslesscover", c.Options.LosslessCover, "Encode the cover as png (lossless) even if the pages use jpeg")
+	c.AddBoolParam(&c.Options.RawPages, "rawpages", c.Options.RawPages, "Copy the original jpeg of the pages that already fit the device, without any filter. The other pages and the cover are converted as usual.")
+	c.AddIntParam(&c.Options.BytesPerPage, "bytesperpage", c.Options.BytesPerPage, "Size budget of each page in bytes: the jpeg quality of the page is lowered down to 30 to fit. 0 = no budget")
 	c.AddFloatParam(&c.Options.AspectRatio, "aspect-ratio", c.Options.AspectRatio, "Aspect ratio (height/width) of the output\n -1 = same as device\n  0 = same as source\n1.6 = amazon advice for kindle")
 	c.AddBoolParam(&c.Options.PortraitOnly, "portrait-only", c.Options.PortraitOnly, "Portrait only: force orientation to portrait only.")
 	c.AddIntParam(&c.Options.TitlePage, "titlepage", c.Options.TitlePage, "Title page\n0 = never\n1 = always\n2 = only if epub is splitted")
+	c.AddBoolParam(&c.Options.VolumeInfoPage, "volumeinfopage", c.Options.VolumeInfoPage, "Insert a page with the title, the volume number and the range of pages at the start of each part when the EPUB is splitted")
+	c.AddIntParam(&c.Options.BlankPage, "blankpage", c.Options.BlankPage, "Insert a blank page before the page N to realign the spreads\n0 = never\n1 = at the start, after the cover")
+	c.AddStringParam(&c.Options.Kindlegen, "kindlegen", c.Options.Kindlegen, "Path of kindlegen, or of a tool with the same arguments, to convert the EPUB when the output ends with .mobi or .azw3")
+	c.AddStringParam(&c.Options.Target, "target", c.Options.Target, "Reader targeted by the fixed layout metadata\ngeneric    = all readers\napplebooks = device aspect ratio and landscape spread unless -aspect-ratio or -portrait-only is given, open to spread\nkobo       = kepub (.kepub.epub) with the kobo spans around the pages")
+	c.AddStringParam(&c.Options.Language, "language", c.Options.Language, "Language of the EPUB (BCP 47), like en, fr or ja, used by the readers to sort and hyphenate")
+	c.AddStringParam(&c.Options.Publisher, "publisher", c.Options.Publisher, "Publisher of the EPUB")
+	c.AddStringParam(&c.Options.OwnerTag, "ownertag", c.Options.OwnerTag, "Owner tag written in the EPUB metadata to identify your copy. This is not a DRM.")
+	c.AddIntParam(&c.Options.Timeout, "timeout", c.Options.Timeout, "Timeout in seconds of each download attempt when the input is an url: 0 = no timeout")
+	c.AddIntParam(&c.Options.Retries, "retries", c.Options.Retries, "Number of retries when the download of an url input fails")
+	c.AddStringParam(&c.Options.StatsLog, "statslog", c.Options.StatsLog, "Append a row to this csv file after each conversion: date, input, outputs, profile, pages, skipped pages, bytes, duration and error")
+	c.AddStringParam(&c.Options.Opds, "opds", c.Options.Opds, "Add an entry to this OPDS 1.2 catalog (atom xml) after each conversion, with the cover written next to the EPUB. The catalog is created if missing.")
 
 	c.AddSection("Default config")
 	c.AddBoolParam(&c.Options.Show, "show", false, "Show your default parameters")
@@ -144,12 +255,17 @@ func (c *Converter) InitParse() {
 	c.AddBoolParam(&c.Options.BestQuality, "bestquality", false, "Max quality: color jpg q100 + noresize")
 	c.AddBoolParam(&c.Options.GreatQuality, "greatquality", false, "Max quality: grayscale jpg q90 + noresize")
 	c.AddBoolParam(&c.Options.GoodQuality, "goodquality", false, "Max quality: grayscale jpg q90")
+	c.AddBoolParam(&c.Options.Color, "color", false, "Keep the colors for the color eInk devices: no grayscale and no dithering, the pages are still cropped, resized and encoded")
 
 	c.AddSection("Other")
 	c.AddIntParam(&c.Options.Workers, "workers", runtime.NumCPU(), "Number of workers")
+	c.AddIntParam(&c.Options.Readers, "readers", 0, "Number of parallel reads of the files of a directory input: 0 = half of the workers, 1 is best for a spinning disk")
+	c.AddIntParam(&c.Options.Decoders, "decoders", 0, "Number of parallel decodings of the images of a directory input: 0 = half of the workers")
 	c.AddBoolParam(&c.Options.Dry, "dry", false, "Dry run to show all options")
 	c.AddBoolParam(&c.Options.DryVerbose, "dry-verbose", false, "Display also sorted files after the TOC")
+	c.AddBoolParam(&c.Options.Preflight, "preflight", false, "Check the input without converting it: dimensions, unreadable images and estimated size")
 	c.AddBoolParam(&c.Options.Quiet, "quiet", false, "Disable progress bar")
+	c.AddBoolParam(&c.Options.ListProfiles, "list-profiles", false, "List the profiles with their resolution, including the profiles file, and exit")
 	c.AddBoolParam(&c.Options.Version, "version", false, "Show current and available version")
 	c.AddBoolParam(&c.Options.Help, "help", false, "Show this help message")
 }
@@ -226,9 +342,50 @@ func (c *Converter) Parse() {
 		os.Exit(0)
 	}
 
+	c.applyEmbeddedSettings()
+	c.applyShortcuts()
+
+	// once for all the inputs of a batch
+	if format := c.ignoredQuality(); format != "" {
+		fmt.Fprintf(os.Stderr, "Warning: quality is ignored with the %s format\n", format)
+	}
+}
+
+// format that ignores the -quality set on the command line, empty if it is used
+func (c *Converter) ignoredQuality() (format string) {
+	if c.Options.Format != "png" && !c.Options.Lossless {
+		return
+	}
+	c.Cmd.Visit(func(f *flag.Flag) {
+		if f.Name == "quality" {
+			format = "png"
+			if c.Options.Lossless {
+				format = "lossless webp"
+			}
+		}
+	})
+	return
+}
+
+// set the options implied by the shortcuts and the target
+func (c *Converter) applyShortcuts() {
 	if c.Options.Auto {
 		c.Options.AutoRotate = true
 		c.Options.AutoSplitDoublePage = true
+		c.Options.AutoDirection = true
+	}
+
+	// an explicit -manga takes precedence over the detection
+	c.Cmd.Visit(func(f *flag.Flag) {
+		if f.Name == "manga" {
+			c.Options.AutoDirection = false
+		}
+	})
+
+	// a kepub has jpeg pages, the format is the one of the images
+	if c.Options.Format == "kepub" {
+		c.Options.Format = "jpeg"
+		c.Options.Target = "kobo"
 	}
 
 	if c.Options.MaxQuality {
@@ -252,12 +409,40 @@ func (c *Converter) Parse() {
 		c.Options.NoResize = false
 	}
 
+	// takes precedence over the grayscale of the quality shortcuts
+	if c.Options.Color {
+		c.Options.Grayscale = false
+	}
+	if c.Options.MaxQuality || c.Options.BestQuality || c.Options.GreatQuality || c.Options.GoodQuality || c.Options.Color {
+		c.Options.GrayscaleOrigin = options.OriginCommandLine
+	}
+
+	if c.Options.Target == "applebooks" {
+		// Apple Books render the fixed layout on the same viewport for every pages, unless asked otherwise
+		set := map[string]bool{}
+		c.Cmd.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+		if !set["aspect-ratio"] {
+			c.Options.AspectRatio = -1
+		}
+		if !set["portrait-only"] {
+			c.Options.PortraitOnly = false
+		}
+	}
+
 	if c.Options.NoFilter {
 		c.Options.Crop = false
 		c.Options.Brightness = 0
 		c.Options.Contrast = 0
+		c.Options.AutoContrast = false
+		c.Options.Gamma = 1
+		c.Options.Sharpen = false
+		c.Options.Dither = false
 		c.Options.AutoRotate = false
 		c.Options.NoBlankImage = false
+		c.Options.Deskew = false
+		c.Options.Descreen = false
 		c.Options.NoResize = true
 	}
 }
@@ -269,19 +454,59 @@ func (c *Converter) Validate() error {
 		return errors.New("missing input")
 	}
 
-	fi, err := os.Stat(c.Options.Input)
-	if err != nil {
-		return err
-	}
-
 	// Check Output
 	var defaultOutput string
-	inputBase := filepath.Clean(c.Options.Input)
-	if fi.IsDir() {
-		defaultOutput = fmt.Sprintf("%s.epub", inputBase)
+	if c.isUrl() {
+		// downloaded into the current directory
+		name := c.urlFileName()
+		if name == "" {
+			return errors.New("input url should end with a file name")
+		}
+		ext := filepath.Ext(name)
+		defaultOutput = fmt.Sprintf("%s.epub", name[0:len(name)-len(ext)])
 	} else {
-		ext := filepath.Ext(inputBase)
-		defaultOutput = fmt.Sprintf("%s.epub", inputBase[0:len(inputBase)-len(ext)])
+		fi, err := os.Stat(c.Options.Input)
+		if err != nil {
+			return err
+		}
+
+		inputBase := filepath.Clean(c.Options.Input)
+		if fi.IsDir() {
+			defaultOutput = fmt.Sprintf("%s.epub", inputBase)
+		} else {
+			ext := filepath.Ext(inputBase)
+			if strings.HasSuffix(strings.ToLower(inputBase), ".tar.gz") {
+				ext = inputBase[len(inputBase)-len(".tar.gz"):]
+			}
+			// name.part1.rar: the volume number is not part of the name
+			if part := regexp.MustCompile(`(?i)\.part[0-9]+\.(rar|cbr)$`).FindString(inputBase); part != "" {
+				ext = part
+			}
+			defaultOutput = fmt.Sprintf("%s.epub", inputBase[0:len(inputBase)-len(ext)])
+		}
+	}
+
+	inputName := strings.TrimSuffix(filepath.Base(defaultOutput), ".epub")
+
+	// KeepPages
+	if c.Options.KeepPages != "" {
+		if !regexp.MustCompile(`^[0-9]+(-[0-9]*)?(,[0-9]+(-[0-9]*)?)*$`).MatchString(c.Options.KeepPages) {
+			return errors.New("keeppages should be a list of pages or ranges, like 10-50,60,80-")
+		}
+		for _, r := range c.Options.GetKeepPages() {
+			if r[0] < 1 || (r[1] != 0 && r[1] < r[0]) {
+				return fmt.Errorf("keeppages: invalid range %d-%d", r[0], r[1])
+			}
+		}
+	}
+
+	// Sample
+	if c.Options.Sample < 0 {
+		return errors.New("sample should be 0 or > 0")
+	}
+	if c.Options.Sample > 0 {
+		// avoid any confusion with the full conversion
+		defaultOutput = fmt.Sprintf("%s [Sample].epub", strings.TrimSuffix(defaultOutput, ".epub"))
 	}
 
 	if c.Options.Output == "" {
@@ -289,7 +514,7 @@ func (c *Converter) Validate() error {
 	}
 
 	c.Options.Output = filepath.Clean(c.Options.Output)
-	if filepath.Ext(c.Options.Output) == ".epub" {
+	if ext := strings.ToLower(filepath.Ext(c.Options.Output)); ext == ".epub" || ext == ".cbz" || ext == ".mobi" || ext == ".azw3" {
 		fo, err := os.Stat(filepath.Dir(c.Options.Output))
 		if err != nil {
 			return err
@@ -303,7 +528,7 @@ func (c *Converter) Validate() error {
 			return err
 		}
 		if !fo.IsDir() {
-			return errors.New("output must be an existing dir or end with .epub")
+			return errors.New("output must be an existing dir or end with .epub, .cbz, .mobi or .azw3")
 		}
 		c.Options.Output = filepath.Join(
 			c.Options.Output,
@@ -311,19 +536,205 @@ func (c *Converter) Validate() error {
 		)
 	}
 
+	// a cbz output can have the name of the input
+	output, _ := filepath.Abs(c.Options.Output)
+	input, _ := filepath.Abs(c.Options.Input)
+	if output == input {
+		return errors.New("output should not replace the input")
+	}
+
+	// the EPUB is converted by kindlegen
+	if c.IsMobi() && !c.Options.Dry {
+		if err := c.checkKindlegen(); err != nil {
+			return err
+		}
+	}
+
+	// Kobo only enables its reader with this extension
+	if c.Options.Target == "kobo" && filepath.Ext(c.Options.Output) == ".epub" && !strings.HasSuffix(c.Options.Output, ".kepub.epub") {
+		c.Options.Output = fmt.Sprintf("%s.kepub.epub", strings.TrimSuffix(c.Options.Output, ".epub"))
+	}
+
+	// Order
+	if c.Options.Order != "" {
+		if _, err := os.Stat(c.Options.Order); err != nil {
+			return err
+		}
+	}
+
+	// Archive Cbz
+	if c.Options.ArchiveCbz != "" {
+		if !strings.EqualFold(filepath.Ext(c.Options.ArchiveCbz), ".cbz") {
+			return errors.New("archivecbz should end with .cbz")
+		}
+		archive, _ := filepath.Abs(c.Options.ArchiveCbz)
+		input, _ := filepath.Abs(c.Options.Input)
+		if archive == input {
+			return errors.New("archivecbz should not replace the input")
+		}
+	}
+
+	// Colophon
+	if c.Options.Colophon != "" {
+		if _, err := os.Stat(c.Options.Colophon); err != nil {
+			return err
+		}
+	}
+
+	// Name Metadata
+	if c.Options.NameMetadata {
+		meta, err := c.parseName(inputName)
+		if err != nil {
+			return err
+		}
+		if meta != nil {
+			if c.Options.Series == "" {
+				c.Options.Series = meta.Series
+			}
+			c.Options.Volume, c.Options.Year = meta.Volume, meta.Year
+			if c.Options.Title == "" {
+				c.Options.Title = meta.Title()
+			}
+			authorSet := false
+			c.Cmd.Visit(func(f *flag.Flag) {
+				authorSet = authorSet || f.Name == "author"
+			})
+			if meta.Author != "" && !authorSet {
+				c.Options.Author = meta.Author
+			}
+		}
+	}
+
+	// Language
+	if !regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`).MatchString(c.Options.Language) {
+		return errors.New("language should be a BCP 47 tag, like en, fr or pt-BR")
+	}
+
+	// Series Index
+	if c.Options.SeriesIndex < 0 && c.Options.SeriesIndex != -1 {
+		return errors.New("series index should be 0 or more, or -1 to read it from the name")
+	}
+	if c.Options.SeriesIndex >= 0 {
+		c.Options.Volume = strconv.FormatFloat(c.Options.SeriesIndex, 'f', -1, 64)
+	}
+
+	// Folder Title
+	if c.Options.FolderTitle && !c.isUrl() {
+		folder, file := c.folderName()
+		if c.Options.Series == "" {
+			c.Options.Series = folder
+		}
+		if c.Options.Title == "" {
+			c.Options.Title = folder
+			if file != "" {
+				c.Options.Title = fmt.Sprintf("%s - %s", folder, file)
+			}
+		}
+	}
+
 	// Title
 	if c.Options.Title == "" {
 		ext := filepath.Ext(defaultOutput)
 		c.Options.Title = filepath.Base(defaultOutput[0 : len(defaultOutput)-len(ext)])
+	} else if c.Options.Sample > 0 {
+		c.Options.Title = fmt.Sprintf("%s [Sample]", c.Options.Title)
+	}
+
+	// Identifier
+	if c.Options.Identifier != "" {
+		id, err := normalizeIdentifier(c.Options.Identifier)
+		if err != nil {
+			return err
+		}
+		c.Options.Identifier = id
 	}
 
 	// Profile
-	if c.Options.Profile == "" {
+	if c.Options.CustomProfile != "" {
+		if !regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*$`).MatchString(c.Options.CustomProfile) {
+			return errors.New("custom profile should be WxH, like 1264x1680")
+		}
+	} else if c.Options.Profile == "" {
 		return errors.New("profile missing")
+	} else if p := c.Options.GetProfile(); p == nil {
+		return fmt.Errorf("profile %q doesn't exists", c.Options.Profile)
 	}
 
-	if p := c.Options.GetProfile(); p == nil {
-		return fmt.Errorf("profile %q doesn't exists", c.Options.Profile)
+	// Spread Mode
+	if !(c.Options.SpreadMode == "both" || c.Options.SpreadMode == "split" || c.Options.SpreadMode == "keep") {
+		return errors.New("spread mode should be both, split or keep")
+	}
+
+	// Double Page Ratio
+	if c.Options.DoublePageRatio < 1 {
+		return errors.New("doublepage ratio should be 1 or more")
+	}
+
+	// Qualities
+	if c.Options.Qualities != "" {
+		if !regexp.MustCompile(`^[0-9]+(,[0-9]+)*$`).MatchString(c.Options.Qualities) {
+			return errors.New("qualities should be a list of qualities, like 70,80,90")
+		}
+		if c.Options.Format != "jpeg" {
+			return errors.New("qualities should be used with the jpeg format")
+		}
+		seen := map[int]bool{}
+		for _, q := range c.Options.GetQualities() {
+			if q < 1 || q > 100 {
+				return errors.New("qualities should be between 1 and 100")
+			}
+			if seen[q] {
+				return fmt.Errorf("quality %d listed more than once", q)
+			}
+			seen[q] = true
+		}
+		c.Options.Quality = c.Options.GetQualities()[0]
+	}
+
+	// Scaled Decode
+	if c.Options.ScaledDecode && !jpegscale.Enabled {
+		return errors.New("scaleddecode needs a build with libjpeg: go install -tags libjpeg")
+	}
+
+	// Long Strip
+	if c.Options.LongStrip && (c.Options.LongStripHeight < 1000 || c.Options.LongStripHeight > 65500) {
+		return errors.New("long strip height should be between 1000 and 65500")
+	}
+
+	// Cover
+	if c.Options.Cover != "" && !c.Options.HasCover {
+		return errors.New("cover should be used with hascover")
+	}
+	if c.Options.CoverOnly && c.Options.Cover == "" {
+		return errors.New("coveronly should be used with cover")
+	}
+
+	// Cover Fit
+	if !(c.Options.CoverFit == "fit" || c.Options.CoverFit == "fill" || c.Options.CoverFit == "pad") {
+		return errors.New("cover fit should be fit, fill or pad")
+	}
+
+	// Cover Ratio
+	if c.Options.CoverRatio != "" && !regexp.MustCompile(`^[1-9][0-9]*:[1-9][0-9]*$`).MatchString(c.Options.CoverRatio) {
+		return errors.New("cover ratio should have the format W:H, like 16:9")
+	}
+
+	// Cover Back
+	if c.Options.CoverBack == 1 {
+		return errors.New("cover back should not be the cover (page 1)")
+	}
+	if c.Options.CoverBack != 0 && !c.Options.HasCover {
+		return errors.New("cover back needs hascover")
+	}
+
+	// RawPages
+	if c.Options.RawPages && c.Options.Format != "jpeg" {
+		return errors.New("rawpages needs the jpeg format")
+	}
+
+	// BytesPerPage
+	if c.Options.BytesPerPage < 0 {
+		return errors.New("bytesperpage should be >= 0")
 	}
 
 	// LimitMb
@@ -331,6 +742,31 @@ func (c *Converter) Validate() error {
 		return errors.New("limitmb should be 0 or >= 20")
 	}
 
+	// LimitFiles
+	if c.Options.LimitFiles < 3 && c.Options.LimitFiles != 0 {
+		return errors.New("limitfiles should be 0 or >= 3")
+	}
+
+	// PartFormat
+	if !strings.Contains(c.Options.PartFormat, "{part}") {
+		return errors.New("partformat should contain {part}")
+	}
+
+	// Crop Threshold
+	if c.Options.CropThreshold < 0 || c.Options.CropThreshold > 255 {
+		return errors.New("crop threshold should be between 0 and 255")
+	}
+
+	// Crop Min Ratio
+	if c.Options.CropMinRatio < 0 || c.Options.CropMinRatio > 1 {
+		return errors.New("crop min ratio should be between 0 and 1")
+	}
+
+	// Crop Padding
+	if c.Options.CropPadding < 0 {
+		return errors.New("crop padding should be 0 or more")
+	}
+
 	// Brightness
 	if c.Options.Brightness < -100 || c.Options.Brightness > 100 {
 		return errors.New("brightness should be between -100 and 100")
@@ -341,11 +777,64 @@ func (c *Converter) Validate() error {
 		return errors.New("contrast should be between -100 and 100")
 	}
 
+	// AutoContrastClip
+	if c.Options.AutoContrastClip < 0 || c.Options.AutoContrastClip >= 50 {
+		return errors.New("autocontrast-clip should be between 0 and 50")
+	}
+
+	// Gamma
+	if c.Options.Gamma < 0.1 || c.Options.Gamma > 3 {
+		return errors.New("gamma should be between 0.1 and 3")
+	}
+
+	// SharpenAmount
+	if c.Options.SharpenAmount <= 0 || c.Options.SharpenAmount > 5 {
+		return errors.New("sharpen-amount should be between 0 and 5")
+	}
+
+	// DitherLevels
+	if c.Options.DitherLevels < 2 || c.Options.DitherLevels > 256 {
+		return errors.New("dither-levels should be between 2 and 256")
+	}
+
+	// MinChapterPages
+	if c.Options.MinChapterPages < 0 {
+		return errors.New("min chapter pages should be 0 or > 0")
+	}
+
 	// SortPathMode
 	if c.Options.SortPathMode < 0 || c.Options.SortPathMode > 2 {
 		return errors.New("sort should be 0, 1 or 2")
 	}
 
+	// RarMode
+	if !(c.Options.RarMode == "auto" || c.Options.RarMode == "solid" || c.Options.RarMode == "random") {
+		return errors.New("rar mode should be auto, solid or random")
+	}
+
+	// PdfDpi
+	if c.Options.PdfDpi < 0 {
+		return errors.New("pdf dpi should be 0 or > 0")
+	}
+
+	// Readers, Decoders
+	if c.Options.Readers < 0 {
+		return errors.New("readers should be 0 or > 0")
+	}
+	if c.Options.Decoders < 0 {
+		return errors.New("decoders should be 0 or > 0")
+	}
+
+	// SamplePages
+	if c.Options.SamplePages < 0 {
+		return errors.New("sample pages should be 0 or > 0")
+	}
+
+	// PdfRender
+	if !(c.Options.PdfRender == "auto" || c.Options.PdfRender == "always" || c.Options.PdfRender == "never") {
+		return errors.New("pdf render should be auto, always or never")
+	}
+
 	// Color
 	colorRegex := regexp.MustCompile("^[0-9A-F]{3}$")
 	if !colorRegex.MatchString(c.Options.ForegroundColor) {
@@ -357,8 +846,44 @@ func (c *Converter) Validate() error {
 	}
 
 	// Format
-	if !(c.Options.Format == "jpeg" || c.Options.Format == "png") {
-		return errors.New("format should be jpeg or png")
+	if !(c.Options.Format == "jpeg" || c.Options.Format == "png" || c.Options.Format == "webp") {
+		return errors.New("format should be jpeg, png, webp or kepub")
+	}
+	if c.Options.Format == "webp" && !webpencode.Enabled {
+		return errors.New("webp format needs a build with webp: go install -tags webp")
+	}
+
+	// Lossless
+	if c.Options.Lossless && c.Options.Format != "webp" {
+		return errors.New("lossless needs the webp format")
+	}
+
+	// Quality
+	if c.Options.Quality < 1 || c.Options.Quality > 100 {
+		return errors.New("quality should be between 1 and 100")
+	}
+
+	// Resize Filter
+	if !(c.Options.ResizeFilter == "nearest" || c.Options.ResizeFilter == "box" || c.Options.ResizeFilter == "bilinear" || c.Options.ResizeFilter == "catmullrom" || c.Options.ResizeFilter == "lanczos") {
+		return errors.New("resize should be nearest, box, bilinear, catmullrom or lanczos")
+	}
+
+	// Fit Mode
+	if !(c.Options.FitMode == "fit" || c.Options.FitMode == "contain" || c.Options.FitMode == "cover" || c.Options.FitMode == "stretch") {
+		return errors.New("fit should be fit, contain, cover or stretch")
+	}
+	if c.Options.FitMode != "fit" && c.Options.LongStrip {
+		return errors.New("fit should be fit with longstrip, the strips have the height of the chapter")
+	}
+
+	// Pad Color
+	if !(c.Options.PadColor == "" || c.Options.PadColor == "white" || c.Options.PadColor == "black") {
+		return errors.New("padcolor should be white or black")
+	}
+
+	// Box Ratio
+	if c.Options.BoxRatio != 0 && c.Options.BoxRatio < 1 {
+		return errors.New("box ratio should be 0 or >= 1")
 	}
 
 	// Aspect Ratio
@@ -372,8 +897,28 @@ func (c *Converter) Validate() error {
 	}
 
 	// Grayscale Mode
-	if c.Options.GrayscaleMode < 0 || c.Options.GrayscaleMode > 2 {
-		return errors.New("grayscale mode should be 0, 1 or 2")
+	if c.Options.GrayscaleMode < 0 || c.Options.GrayscaleMode > 3 {
+		return errors.New("grayscale mode should be 0, 1, 2 or 3")
+	}
+
+	// Blank Page
+	if c.Options.BlankPage < 0 {
+		return errors.New("blank page should be 0 or > 0")
+	}
+
+	// Target
+	if !(c.Options.Target == "generic" || c.Options.Target == "applebooks" || c.Options.Target == "kobo") {
+		return errors.New("target should be generic, applebooks or kobo")
+	}
+
+	// Timeout
+	if c.Options.Timeout < 0 {
+		return errors.New("timeout should be 0 or > 0")
+	}
+
+	// Retries
+	if c.Options.Retries < 0 {
+		return errors.New("retries should be 0 or > 0")
 	}
 
 	return nil
@@ -397,3 +942,13 @@ func (c *Converter) Stats() {
 		mem.Sys/1024/1024,
 	)
 }
+
+// name of the folder of the input, and the name of the file without extension if the input is not a directory
+func (c *Converter) folderName() (folder string, file string) {
+	input, _ := filepath.Abs(c.Options.Input)
+	if fi, err := os.Stat(input); err == nil && fi.IsDir() {
+		return filepath.Base(input), ""
+	}
+	file = filepath.Base(input)
+	return filepath.Base(filepath.Dir(input)), strings.TrimSuffix(file, filepath.Ext(file))
+}
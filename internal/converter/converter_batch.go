@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/converter/options"
+)
+
+// Read the inputs of the batch manifest.
+//
+// one input per line, the empty lines and the lines starting with a # are ignored.
+func (c *Converter) BatchInputs() ([]string, error) {
+	if c.Options.Input != "" {
+		return nil, errors.New("batch and input are exclusive")
+	}
+	if c.Options.Identifier != "" {
+		return nil, errors.New("the identifier can't be shared by the inputs of a batch")
+	}
+	// each input would overwrite the same epub
+	if c.Options.Output != "" {
+		if fo, err := os.Stat(c.Options.Output); err != nil || !fo.IsDir() {
+			return nil, errors.New("the output of a batch should be an existing dir")
+		}
+	}
+
+	f, err := os.Open(c.Options.Batch)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	inputs := make([]string, 0)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("batch: %w", err)
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("batch: no input found")
+	}
+	return inputs, nil
+}
+
+// Prepare the conversion of an input of the batch, with the options of the command line.
+//
+// the options are restored in place, the flags point to them. The settings embedded in the input and the
+// colors of the profile are applied like for a single input. In a recursive conversion, the output keeps the sub directory of the input.
+func (c *Converter) StartInput(base options.Options, input string) {
+	*c.Options = base
+	c.Options.Input = input
+	if base.Recursive && base.Input != "" && base.Output != "" {
+		c.Options.Output = c.recursiveOutput(base.Input, base.Output, input)
+	}
+	c.startAt = time.Now()
+	c.applyEmbeddedSettings()
+	c.applyShortcuts()
+	c.ApplyProfile()
+}
+
+// Report the inputs of the batch which failed, after the conversion of all of them.
+//
+// errs has the error of each input, nil if converted.
+func (c *Converter) BatchReport(inputs []string, errs []error) {
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\nBatch: %d converted, %d failed\n", len(inputs)-failed, failed)
+	for i, input := range inputs {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "  - %s: %s\n", filepath.Clean(input), errs[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/converter/options"
+)
+
+// cbz with a gcc.conf, no settings if empty
+func settingsCbz(t *testing.T, path, settings string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	if settings != "" {
+		fw, err := w.Create(embeddedSettingsName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(settings)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartInputProfile(t *testing.T) {
+	dir := t.TempDir()
+	gray, color, plain := filepath.Join(dir, "gray.cbz"), filepath.Join(dir, "color.cbz"), filepath.Join(dir, "plain.cbz")
+	settingsCbz(t, gray, "profile: KV\n")
+	settingsCbz(t, color, "profile: KoLC\n")
+	settingsCbz(t, plain, "")
+
+	// a color profile saved in the config, the base is taken before it is applied
+	conv := parsed(t, "-quiet")
+	conv.Options.Profile = "KoLC"
+	conv.Options.ProfileOrigin = options.OriginConfig
+	base := *conv.Options
+	conv.ApplyProfile()
+
+	for _, c := range []struct {
+		input string
+		want  bool
+	}{
+		{gray, true},
+		{plain, false},
+		{color, false},
+		{gray, true},
+	} {
+		conv.StartInput(base, c.input)
+		if conv.Options.Grayscale != c.want {
+			t.Errorf("%s: grayscale = %v, want %v", filepath.Base(c.input), conv.Options.Grayscale, c.want)
+		}
+	}
+}
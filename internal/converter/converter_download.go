@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// check if the input is an url to download
+func (c *Converter) isUrl() bool {
+	u, err := url.Parse(c.Options.Input)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// name of the file to download
+func (c *Converter) urlFileName() string {
+	u, err := url.Parse(c.Options.Input)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// Download the input if it is an url, and return the local path to use.
+//
+// Each attempt is limited by the timeout, and failures are retried with an exponential backoff.
+func (c *Converter) Download() (string, error) {
+	if !c.isUrl() {
+		return c.Options.Input, nil
+	}
+
+	dir, err := os.MkdirTemp("", "go-comic-converter-download-")
+	if err != nil {
+		return "", err
+	}
+	c.downloadDir = dir
+	filename := filepath.Join(dir, c.urlFileName())
+
+	client := &http.Client{Timeout: time.Duration(c.Options.Timeout) * time.Second}
+	for attempt := 0; ; attempt++ {
+		retry, err := c.download(client, filename)
+		if err == nil {
+			return filename, nil
+		}
+		if !retry || attempt >= c.Options.Retries {
+			return "", fmt.Errorf("download %s failed after %d attempt(s): %w", c.Options.Input, attempt+1, err)
+		}
+		backoff := time.Duration(1<<attempt) * time.Second
+		fmt.Fprintf(os.Stderr, "download failed: %s, retrying in %s\n", err, backoff)
+		time.Sleep(backoff)
+	}
+}
+
+// download the input once, and report if the failure is worth a retry
+func (c *Converter) download(client *http.Client, filename string) (retry bool, err error) {
+	resp, err := client.Get(c.Options.Input)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// client errors will not be fixed by retrying
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		return true, err
+	}
+	return false, f.Close()
+}
+
+// Remove the downloaded input, and the EPUB converted to mobi
+func (c *Converter) Clean() {
+	if c.downloadDir != "" {
+		os.RemoveAll(c.downloadDir)
+		c.downloadDir = ""
+	}
+	if c.mobiDir != "" {
+		os.RemoveAll(c.mobiDir)
+		c.mobiDir = ""
+	}
+}
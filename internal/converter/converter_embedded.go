@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	epubimageprocessor "github.com/celogeek/go-comic-converter/v2/internal/epub/imageprocessor"
+)
+
+// name of the settings file at the root of an archive
+const embeddedSettingsName = "gcc.conf"
+
+// prefix of an archive comment with settings
+const embeddedSettingsComment = "gcc:"
+
+// apply the settings embedded in the archive input over the default config.
+//
+// the parameters set on the command line take precedence, they are set again after the embedded settings.
+func (c *Converter) applyEmbeddedSettings() {
+	if c.Options.NoEmbeddedSettings || c.Options.Save || c.Options.Input == "" || c.isUrl() {
+		return
+	}
+
+	data, source, err := c.readEmbeddedSettings()
+	if err == nil && data == nil {
+		return
+	}
+
+	set := map[string]string{}
+	c.Cmd.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	if err == nil {
+		err = c.Options.LoadEmbeddedConfig(data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring the embedded settings %s: %s\n", source, err)
+	}
+
+	for name, value := range set {
+		c.Cmd.Set(name, value)
+	}
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "Using the embedded settings %s\n", source)
+	}
+}
+
+// content of the gcc.conf of the archive, or of its comment starting with "gcc:".
+//
+// the archive is opened like for the conversion, with the password and from the first volume of a rar.
+func (c *Converter) readEmbeddedSettings() (data []byte, source string, err error) {
+	data, source, err = epubimageprocessor.ReadArchiveFile(c.Options.Input, c.Options.Password, embeddedSettingsName)
+	if err != nil && source == "" {
+		// the conversion reports the archive that can't be opened
+		return nil, "", nil
+	}
+	if source != "" {
+		return data, source, err
+	}
+
+	switch strings.ToLower(filepath.Ext(c.Options.Input)) {
+	case ".cbz", ".zip":
+		r, err := zip.OpenReader(c.Options.Input)
+		if err != nil {
+			return nil, "", nil
+		}
+		defer r.Close()
+		if strings.HasPrefix(r.Comment, embeddedSettingsComment) {
+			return []byte(strings.TrimPrefix(r.Comment, embeddedSettingsComment)), "comment", nil
+		}
+	}
+	return nil, "", nil
+}
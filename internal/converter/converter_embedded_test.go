@@ -0,0 +1,54 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	cryptozip "github.com/yeka/zip"
+)
+
+// cbz with an encrypted gcc.conf
+func encryptedCbz(t *testing.T, path, password, settings string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := cryptozip.NewWriter(f)
+	fw, err := w.Encrypt(embeddedSettingsName, password, cryptozip.AES256Encryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte(settings)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadEmbeddedSettings(t *testing.T) {
+	dir := t.TempDir()
+	plain, encrypted := filepath.Join(dir, "plain.cbz"), filepath.Join(dir, "encrypted.cbz")
+	settingsCbz(t, plain, "profile: KV\n")
+	encryptedCbz(t, encrypted, "secret", "profile: KoLC\n")
+
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-input", plain}, "profile: KV\n"},
+		{[]string{"-input", encrypted, "-password", "secret"}, "profile: KoLC\n"},
+		// the conversion reports the missing password
+		{[]string{"-input", encrypted}, ""},
+	} {
+		data, source, err := parsed(t, c.args...).readEmbeddedSettings()
+		if err != nil {
+			t.Fatalf("%q: %v", c.args, err)
+		}
+		if string(data) != c.want || (c.want != "" && source != embeddedSettingsName) {
+			t.Errorf("%q: settings %q from %q, want %q", c.args, data, source, c.want)
+		}
+	}
+}
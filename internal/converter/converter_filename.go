@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// metadata found in the name of the input
+type nameMetadata struct {
+	Series, Author, Volume, Chapter, Year string
+}
+
+// compile the pattern of the input name, the series is mandatory
+func (c *Converter) nameRegex() (*regexp.Regexp, error) {
+	r, err := regexp.Compile(c.Options.NameRegex)
+	if err != nil {
+		return nil, fmt.Errorf("name regex: %w", err)
+	}
+	if r.SubexpIndex("series") == -1 {
+		return nil, errors.New("name regex should define the group (?P<series>...)")
+	}
+	return r, nil
+}
+
+// extract the metadata from the name of the input with the named groups of the pattern:
+// series, author, volume, chapter and year.
+func (c *Converter) parseName(name string) (*nameMetadata, error) {
+	r, err := c.nameRegex()
+	if err != nil {
+		return nil, err
+	}
+
+	m := r.FindStringSubmatch(name)
+	if m == nil {
+		return nil, nil
+	}
+	group := func(n string) string {
+		if i := r.SubexpIndex(n); i != -1 {
+			return strings.TrimSpace(m[i])
+		}
+		return ""
+	}
+	// remove the leading zeros of the numbers
+	number := func(n string) string {
+		v := group(n)
+		if i, err := strconv.Atoi(v); err == nil {
+			return fmt.Sprint(i)
+		}
+		return v
+	}
+
+	meta := &nameMetadata{
+		Series:  group("series"),
+		Author:  group("author"),
+		Volume:  number("volume"),
+		Chapter: number("chapter"),
+		Year:    group("year"),
+	}
+	if meta.Series == "" {
+		return nil, nil
+	}
+	return meta, nil
+}
+
+// title built from the metadata of the name
+func (m *nameMetadata) Title() string {
+	title := m.Series
+	if m.Volume != "" {
+		title = fmt.Sprintf("%s Vol. %s", title, m.Volume)
+	}
+	if m.Chapter != "" {
+		title = fmt.Sprintf("%s Ch. %s", title, m.Chapter)
+	}
+	return title
+}
@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// normalize the identifier of the EPUB into an urn: uuid or isbn.
+func normalizeIdentifier(id string) (string, error) {
+	lower := strings.ToLower(id)
+	switch {
+	case strings.HasPrefix(lower, "urn:uuid:"):
+		id = id[len("urn:uuid:"):]
+	case strings.HasPrefix(lower, "urn:isbn:"):
+		return normalizeIsbn(id[len("urn:isbn:"):])
+	}
+
+	if u, err := uuid.FromString(id); err == nil {
+		return fmt.Sprintf("urn:uuid:%s", u), nil
+	}
+	if isbn, err := normalizeIsbn(id); err == nil {
+		return isbn, nil
+	}
+	return "", errors.New("identifier should be an uuid or an isbn")
+}
+
+// check the isbn 10 or 13 with its check digit
+func normalizeIsbn(isbn string) (string, error) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	sum := 0
+	switch len(isbn) {
+	case 10:
+		for i, c := range isbn {
+			v := int(c - '0')
+			if c == 'X' && i == 9 {
+				v = 10
+			} else if c < '0' || c > '9' {
+				return "", errors.New("invalid isbn")
+			}
+			sum += v * (10 - i)
+		}
+		if sum%11 != 0 {
+			return "", errors.New("invalid isbn check digit")
+		}
+	case 13:
+		for i, c := range isbn {
+			if c < '0' || c > '9' {
+				return "", errors.New("invalid isbn")
+			}
+			v := int(c - '0')
+			if i%2 == 1 {
+				v *= 3
+			}
+			sum += v
+		}
+		if sum%10 != 0 {
+			return "", errors.New("invalid isbn check digit")
+		}
+	default:
+		return "", errors.New("isbn should have 10 or 13 digits")
+	}
+	return fmt.Sprintf("urn:isbn:%s", isbn), nil
+}
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// check if the output is converted to mobi (or azw3) by kindlegen
+func (c *Converter) IsMobi() bool {
+	ext := strings.ToLower(filepath.Ext(c.Options.Output))
+	return ext == ".mobi" || ext == ".azw3"
+}
+
+// check that kindlegen can be run before the conversion
+func (c *Converter) checkKindlegen() error {
+	if _, err := exec.LookPath(c.Options.Kindlegen); err != nil {
+		return fmt.Errorf("kindlegen not found (%s), install it or set its path with -kindlegen", c.Options.Kindlegen)
+	}
+	return nil
+}
+
+// Output of the EPUB to convert to mobi, in a temporary directory removed by Clean.
+func (c *Converter) MobiOutput() (string, error) {
+	dir, err := os.MkdirTemp("", "gcc-mobi-")
+	if err != nil {
+		return "", err
+	}
+	c.mobiDir = dir
+	name := strings.TrimSuffix(filepath.Base(c.Options.Output), filepath.Ext(c.Options.Output))
+	return filepath.Join(dir, name+".epub"), nil
+}
+
+// Convert each EPUB written in the temporary directory with kindlegen, and move the result next to the output.
+//
+// the output of kindlegen is streamed to stderr. kindlegen exits with 1 on warnings, the conversion is
+// successful as long as the file is created.
+func (c *Converter) Mobi(epubs []string) ([]string, error) {
+	ext := filepath.Ext(c.Options.Output)
+	outputs := make([]string, 0, len(epubs))
+	for _, epub := range epubs {
+		name := strings.TrimSuffix(filepath.Base(epub), filepath.Ext(epub)) + ext
+		cmd := exec.Command(c.Options.Kindlegen, epub, "-o", name)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		runErr := cmd.Run()
+
+		mobi := filepath.Join(filepath.Dir(epub), name)
+		if _, err := os.Stat(mobi); err != nil {
+			if runErr == nil {
+				runErr = errors.New("no file created")
+			}
+			return outputs, fmt.Errorf("kindlegen failed for %s: %w", filepath.Base(epub), runErr)
+		}
+
+		output := filepath.Join(filepath.Dir(c.Options.Output), name)
+		if err := moveFile(mobi, output); err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// move a file, copied if the temporary directory is on another device
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,145 @@
+package converter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// OPDS 1.2 acquisition feed, the entries are added before its end
+const opdsFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>urn:uuid:%s</id>
+  <title>go-comic-converter</title>
+  <updated>%s</updated>
+  <author><name>go-comic-converter</name></author>
+  <link rel="self" href="%s" type="application/atom+xml;profile=opds-catalog;kind=acquisition"/>
+</feed>
+`
+
+type opdsLink struct {
+	Rel   string `xml:"rel,attr"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type opdsEntry struct {
+	XMLName xml.Name   `xml:"entry"`
+	Title   string     `xml:"title"`
+	Id      string     `xml:"id"`
+	Updated string     `xml:"updated"`
+	Author  string     `xml:"author>name"`
+	Links   []opdsLink `xml:"link"`
+}
+
+// Add an entry of the converted EPUB to the OPDS catalog if requested.
+//
+// the cover is written next to the EPUB as a jpeg, each part of the EPUB is an acquisition link.
+// the links are relative to the catalog.
+func (c *Converter) OpdsEntry(uid string, outputs []string, cover image.Image) error {
+	if c.Options.Opds == "" || len(outputs) == 0 {
+		return nil
+	}
+
+	catalogDir := filepath.Dir(c.Options.Opds)
+	href := func(path string) string {
+		rel, err := filepath.Rel(catalogDir, path)
+		if err != nil {
+			rel = path
+		}
+		segments := strings.Split(filepath.ToSlash(rel), "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		return strings.Join(segments, "/")
+	}
+
+	entry := &opdsEntry{
+		Title:   c.Options.Title,
+		Id:      uid,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  c.Options.Author,
+	}
+
+	if cover != nil {
+		coverPath := strings.TrimSuffix(strings.TrimSuffix(c.Options.Output, filepath.Ext(c.Options.Output)), ".kepub") + ".jpg"
+		if err := writeJpeg(coverPath, cover, c.Options.Quality); err != nil {
+			return fmt.Errorf("opds: %w", err)
+		}
+		entry.Links = append(entry.Links,
+			opdsLink{Rel: "http://opds-spec.org/image", Href: href(coverPath), Type: "image/jpeg"},
+			opdsLink{Rel: "http://opds-spec.org/image/thumbnail", Href: href(coverPath), Type: "image/jpeg"},
+		)
+	}
+
+	for i, output := range outputs {
+		title := ""
+		if len(outputs) > 1 {
+			title = fmt.Sprintf("Part %d of %d", i+1, len(outputs))
+		}
+		entry.Links = append(entry.Links, opdsLink{
+			Rel:   "http://opds-spec.org/acquisition",
+			Href:  href(output),
+			Type:  opdsType(output),
+			Title: title,
+		})
+	}
+
+	b, err := xml.MarshalIndent(entry, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("opds: %w", err)
+	}
+
+	feed, err := os.ReadFile(c.Options.Opds)
+	if os.IsNotExist(err) {
+		feed, err = []byte(fmt.Sprintf(opdsFeed, uuid.Must(uuid.NewV4()), entry.Updated, url.PathEscape(filepath.Base(c.Options.Opds)))), nil
+	}
+	if err != nil {
+		return fmt.Errorf("opds: %w", err)
+	}
+
+	end := strings.LastIndex(string(feed), "</feed>")
+	if end < 0 {
+		return fmt.Errorf("opds: %s is not an atom feed", c.Options.Opds)
+	}
+	content := fmt.Sprintf("%s%s\n%s", feed[:end], b, feed[end:])
+	if err := os.WriteFile(c.Options.Opds, []byte(content), 0644); err != nil {
+		return fmt.Errorf("opds: %w", err)
+	}
+	return nil
+}
+
+func writeJpeg(path string, img image.Image, quality int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// media type of an output, from its extension
+func opdsType(output string) string {
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".cbz":
+		return "application/vnd.comicbook+zip"
+	case ".mobi":
+		return "application/x-mobipocket-ebook"
+	case ".azw3":
+		return "application/vnd.amazon.ebook"
+	default:
+		return "application/epub+zip"
+	}
+}
@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	epubimageprocessor "github.com/celogeek/go-comic-converter/v2/internal/epub/imageprocessor"
+)
+
+// Find the books to convert one by one when the input is a directory of archives.
+//
+// The directory is a collection when its tree contains at least one archive (cbz, cbr, pdf...). Each archive is
+// a book, and so is each sub directory with images but no archive in its tree, converted as a directory of images.
+// The art of the folders (cover.jpg, folder.jpg) is not an image of a book, the other images beside the archives are ignored.
+// Without archive, nil is returned and the directory is converted as a directory of images, like without -recursive.
+// The hidden files and directories are ignored, and a rar split in volumes is converted once, from its first volume.
+func (c *Converter) RecursiveInputs() ([]string, error) {
+	fi, err := os.Stat(c.Options.Input)
+	if err != nil || !fi.IsDir() {
+		return nil, err
+	}
+
+	root := filepath.Clean(c.Options.Input)
+	// the directories and the archives in the order of the walk, and what the tree of each directory has
+	entries := make([]string, 0)
+	dirs, archives, images := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	mark := func(m map[string]bool, dir string) {
+		for ; !m[dir]; dir = filepath.Dir(dir) {
+			m[dir] = true
+			if dir == root {
+				break
+			}
+		}
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "__MACOSX") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			entries = append(entries, path)
+			dirs[path] = true
+			return nil
+		}
+		if epubimageprocessor.IsRarNextVolume(path) {
+			return nil
+		}
+		if epubimageprocessor.IsSupportedInput(path) {
+			entries = append(entries, path)
+			mark(archives, filepath.Dir(path))
+		} else if epubimageprocessor.IsSupportedImage(path) && !epubimageprocessor.IsCoverFile(path) {
+			mark(images, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !archives[root] {
+		return nil, nil
+	}
+
+	inputs := make([]string, 0)
+	for _, entry := range entries {
+		switch {
+		case !dirs[entry]:
+			inputs = append(inputs, entry)
+		// the top directory of a book, its parent is in the collection
+		case images[entry] && !archives[entry] && archives[filepath.Dir(entry)]:
+			inputs = append(inputs, entry)
+		}
+	}
+
+	if c.Options.Output != "" {
+		if fo, err := os.Stat(c.Options.Output); err != nil || !fo.IsDir() {
+			return nil, errors.New("the output of a recursive conversion should be an existing dir")
+		}
+	}
+	if c.Options.Identifier != "" {
+		return nil, errors.New("the identifier can't be shared by the inputs of a recursive conversion")
+	}
+	return inputs, nil
+}
+
+// Output directory of an archive of the collection: the same sub directory inside the output.
+func (c *Converter) recursiveOutput(root, output, input string) string {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Dir(input))
+	if err != nil {
+		return output
+	}
+	dir := filepath.Join(output, rel)
+	if !c.Options.Dry {
+		os.MkdirAll(dir, 0755)
+	}
+	return dir
+}
@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// empty files, only the names are read
+func tree(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRecursiveInputs(t *testing.T) {
+	dir := t.TempDir()
+	tree(t, dir,
+		"folder.jpg",
+		"loose.png",
+		"serie/cover.jpg",
+		"serie/vol1.cbz",
+		"serie/vol2.cbr",
+		"book/ch1/001.jpg",
+		"book/ch2/001.jpg",
+		"book/folder.jpg",
+		"other/001.png",
+		"art/folder.jpg",
+		".hidden/vol.cbz",
+	)
+
+	inputs, err := parsed(t, "-input", dir, "-quiet").RecursiveInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "book"),
+		filepath.Join(dir, "other"),
+		filepath.Join(dir, "serie", "vol1.cbz"),
+		filepath.Join(dir, "serie", "vol2.cbr"),
+	}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("inputs = %q, want %q", inputs, want)
+	}
+}
+
+func TestRecursiveInputsImages(t *testing.T) {
+	dir := t.TempDir()
+	tree(t, dir, "cover.jpg", "ch1/001.jpg", "ch2/001.jpg")
+
+	inputs, err := parsed(t, "-input", dir, "-quiet").RecursiveInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inputs != nil {
+		t.Errorf("inputs = %q, want the directory converted as one comic", inputs)
+	}
+}
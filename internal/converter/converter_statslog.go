@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Append the result of the conversion to the stats log if requested.
+//
+// The header is written when the file is created, the outputs are separated by a "|".
+func (c *Converter) StatsLog(outputs []string, pages, skipped int, convErr error) error {
+	if c.Options.StatsLog == "" {
+		return nil
+	}
+
+	var size int64
+	for _, output := range outputs {
+		if fi, err := os.Stat(output); err == nil {
+			size += fi.Size()
+		}
+	}
+
+	errMsg := ""
+	if convErr != nil {
+		errMsg = convErr.Error()
+	}
+
+	f, err := os.OpenFile(c.Options.StatsLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("stats log: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stats log: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	if fi.Size() == 0 {
+		w.Write([]string{"date", "input", "outputs", "profile", "pages", "skipped", "bytes", "duration", "error"})
+	}
+	w.Write([]string{
+		c.startAt.Format(time.RFC3339),
+		c.Options.Input,
+		strings.Join(outputs, "|"),
+		c.Options.GetProfile().Code,
+		fmt.Sprint(pages),
+		fmt.Sprint(skipped),
+		fmt.Sprint(size),
+		fmt.Sprintf("%.3f", time.Since(c.startAt).Seconds()),
+		errMsg,
+	})
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("stats log: %w", err)
+	}
+	return f.Close()
+}
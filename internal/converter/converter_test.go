@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/converter/options"
+)
+
+// converter with the flags parsed, before the shortcuts
+func parsed(t *testing.T, args ...string) *Converter {
+	c := New()
+	c.InitParse()
+	if err := c.Cmd.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAppleBooksTarget(t *testing.T) {
+	for _, c := range []struct {
+		args         []string
+		aspectRatio  float64
+		portraitOnly bool
+	}{
+		{[]string{"-target", "applebooks"}, -1, false},
+		{[]string{"-target", "applebooks", "-portrait-only"}, -1, true},
+		{[]string{"-target", "applebooks", "-aspect-ratio", "1.5"}, 1.5, false},
+	} {
+		conv := parsed(t, c.args...)
+		conv.applyShortcuts()
+		if conv.Options.AspectRatio != c.aspectRatio || conv.Options.PortraitOnly != c.portraitOnly {
+			t.Errorf("%v: aspect ratio %v, portrait only %v, want %v and %v", c.args, conv.Options.AspectRatio, conv.Options.PortraitOnly, c.aspectRatio, c.portraitOnly)
+		}
+	}
+}
+
+func TestColorProfileGrayscale(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		args   []string
+		config bool // grayscale and profile saved in the config
+		want   bool
+	}{
+		{"profile", []string{"-profile", "KoLC"}, false, false},
+		{"-grayscale", []string{"-profile", "KoLC", "-grayscale"}, false, true},
+		{"-greatquality", []string{"-profile", "KoLC", "-greatquality"}, false, true},
+		{"-goodquality", []string{"-profile", "KoLC", "-goodquality"}, false, true},
+		{"saved config", nil, true, true},
+		{"profile over the saved config", []string{"-profile", "KoCC"}, true, false},
+	} {
+		conv := New()
+		if c.config {
+			conv.Options.Profile = "KoLC"
+			conv.Options.Grayscale = true
+			conv.Options.ProfileOrigin = options.OriginConfig
+			conv.Options.GrayscaleOrigin = options.OriginConfig
+		}
+		conv.InitParse()
+		if err := conv.Cmd.Parse(c.args); err != nil {
+			t.Fatal(err)
+		}
+		conv.applyShortcuts()
+		conv.ApplyProfile()
+		if conv.Options.Grayscale != c.want {
+			t.Errorf("%s: grayscale = %v, want %v", c.name, conv.Options.Grayscale, c.want)
+		}
+	}
+}
+
+func TestFormatKepub(t *testing.T) {
+	conv := parsed(t, "-format", "kepub")
+	conv.applyShortcuts()
+	if conv.Options.Format != "jpeg" || conv.Options.Target != "kobo" {
+		t.Errorf("format %q target %q, want jpeg and kobo", conv.Options.Format, conv.Options.Target)
+	}
+}
+
+func TestSeriesIndex(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "Serie v03.cbz")
+	settingsCbz(t, input, "")
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "3"},
+		{[]string{"-seriesindex", "0"}, "0"},
+		{[]string{"-seriesindex", "2.5"}, "2.5"},
+	} {
+		conv := parsed(t, append([]string{"-input", input, "-profile", "KV", "-namemetadata", "-quiet"}, c.args...)...)
+		if err := conv.Validate(); err != nil {
+			t.Fatalf("%q: %v", c.args, err)
+		}
+		if conv.Options.Volume != c.want {
+			t.Errorf("%q: volume %q, want %q", c.args, conv.Options.Volume, c.want)
+		}
+	}
+
+	if err := parsed(t, "-input", input, "-profile", "KV", "-seriesindex", "-2").Validate(); err == nil {
+		t.Error("a negative series index is accepted")
+	}
+}
+
+func TestIgnoredQuality(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-format", "png"}, ""},
+		{[]string{"-format", "png", "-quality", "90"}, "png"},
+		{[]string{"-format", "webp", "-lossless", "-quality", "90"}, "lossless webp"},
+		{[]string{"-format", "jpeg", "-quality", "90"}, ""},
+	} {
+		if got := parsed(t, c.args...).ignoredQuality(); got != c.want {
+			t.Errorf("%q: quality ignored with %q, want %q", c.args, got, c.want)
+		}
+	}
+}
@@ -5,48 +5,141 @@ package options
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/celogeek/go-comic-converter/v2/internal/converter/profiles"
 	"gopkg.in/yaml.v3"
 )
 
+// origin of a setting, a later origin takes precedence
+const (
+	OriginDefault = iota
+	OriginConfig
+	OriginEmbedded
+	OriginCommandLine
+)
+
 type Options struct {
 	// Output
-	Input  string `yaml:"-"`
-	Output string `yaml:"-"`
-	Author string `yaml:"-"`
-	Title  string `yaml:"-"`
+	Input      string `yaml:"-"`
+	Batch      string `yaml:"-"`
+	Recursive  bool   `yaml:"-"`
+	Output     string `yaml:"-"`
+	Author     string `yaml:"-"`
+	Title      string `yaml:"-"`
+	Identifier string `yaml:"-"`
+	Panels     string `yaml:"-"`
+	Order      string `yaml:"-"`
+	Sample     int    `yaml:"-"`
+	KeepPages  string `yaml:"-"`
+	Colophon   string `yaml:"-"`
+	ArchiveCbz string `yaml:"-"`
+
+	// Name Metadata
+	NameMetadata bool    `yaml:"name_metadata"`
+	NameRegex    string  `yaml:"name_regex"`
+	FolderTitle  bool    `yaml:"folder_title"`
+	Series       string  `yaml:"-"`
+	Volume       string  `yaml:"-"`
+	SeriesIndex  float64 `yaml:"-"`
+	Year         string  `yaml:"-"`
 
 	// Config
 	Profile                    string  `yaml:"profile"`
+	ProfilesFile               string  `yaml:"profiles_file"`
+	CustomProfile              string  `yaml:"custom_profile"`
 	Quality                    int     `yaml:"quality"`
+	Qualities                  string  `yaml:"-"`
 	Grayscale                  bool    `yaml:"grayscale"`
-	GrayscaleMode              int     `yaml:"grayscale_mode"` // 0 = normal, 1 = average, 2 = luminance
+	GrayscaleMode              int     `yaml:"grayscale_mode"` // 0 = normal, 1 = average, 2 = luminance, 3 = colorblind
 	Crop                       bool    `yaml:"crop"`
 	CropRatioLeft              int     `yaml:"crop_ratio_left"`
 	CropRatioUp                int     `yaml:"crop_ratio_up"`
 	CropRatioRight             int     `yaml:"crop_ratio_right"`
 	CropRatioBottom            int     `yaml:"crop_ratio_bottom"`
+	CropThreshold              int     `yaml:"crop_threshold"`
+	CropMinRatio               float64 `yaml:"crop_min_ratio"`
+	CropPadding                int     `yaml:"crop_padding"`
+	CropFromFirst              bool    `yaml:"crop_from_first"`
+	Deskew                     bool    `yaml:"deskew"`
+	Descreen                   bool    `yaml:"descreen"`
+	ScaledDecode               bool    `yaml:"scaled_decode"`
 	Brightness                 int     `yaml:"brightness"`
 	Contrast                   int     `yaml:"contrast"`
+	AutoContrast               bool    `yaml:"auto_contrast"`
+	AutoContrastClip           float64 `yaml:"auto_contrast_clip"`
+	Gamma                      float64 `yaml:"gamma"`
+	Sharpen                    bool    `yaml:"sharpen"`
+	SharpenAmount              float64 `yaml:"sharpen_amount"`
+	Dither                     bool    `yaml:"dither"`
+	DitherLevels               int     `yaml:"dither_levels"`
 	AutoRotate                 bool    `yaml:"auto_rotate"`
 	AutoSplitDoublePage        bool    `yaml:"auto_split_double_page"`
+	SpreadMode                 string  `yaml:"spread_mode"`
+	DoublePageRatio            float64 `yaml:"double_page_ratio"`
+	LongStrip                  bool    `yaml:"long_strip"`
+	LongStripHeight            int     `yaml:"long_strip_height"`
 	NoBlankImage               bool    `yaml:"no_blank_image"`
 	Manga                      bool    `yaml:"manga"`
+	AutoDirection              bool    `yaml:"auto_direction"`
 	HasCover                   bool    `yaml:"has_cover"`
+	NoCoverFile                bool    `yaml:"no_cover_file"`
+	Cover                      string  `yaml:"-"`
+	CoverOnly                  bool    `yaml:"cover_only"`
+	CoverFit                   string  `yaml:"cover_fit"`
+	CoverRatio                 string  `yaml:"cover_ratio"`
+	CoverBack                  int     `yaml:"-"`
 	LimitMb                    int     `yaml:"limit_mb"`
+	LimitFiles                 int     `yaml:"limit_files"`
+	PartFormat                 string  `yaml:"part_format"`
+	NoZip64                    bool    `yaml:"no_zip64"`
+	LimitExcludeCover          bool    `yaml:"limit_exclude_cover"`
 	StripFirstDirectoryFromToc bool    `yaml:"strip_first_directory_from_toc"`
+	MinChapterPages            int     `yaml:"min_chapter_pages"`
+	TocThumbnails              bool    `yaml:"toc_thumbnails"`
 	SortPathMode               int     `yaml:"sort_path_mode"`
+	ReverseOrder               bool    `yaml:"-"`
+	IncludeHidden              bool    `yaml:"include_hidden"`
+	WarnUnsupported            bool    `yaml:"warn_unsupported"`
+	SniffContent               bool    `yaml:"sniff_content"`
+	RarMode                    string  `yaml:"rar_mode"`
+	Password                   string  `yaml:"-"`
+	CheckArchive               bool    `yaml:"check_archive"`
+	SkipBad                    bool    `yaml:"skip_bad"`
+	NoEmbeddedSettings         bool    `yaml:"no_embedded_settings"`
+	PdfDpi                     int     `yaml:"pdf_dpi"`
+	PdfRender                  string  `yaml:"pdf_render"`
+	SamplePages                int     `yaml:"sample_pages"`
 	ForegroundColor            string  `yaml:"foreground_color"`
 	BackgroundColor            string  `yaml:"background_color"`
 	NoResize                   bool    `yaml:"noresize"`
+	BoxRatio                   float64 `yaml:"box_ratio"`
+	ResizeFilter               string  `yaml:"resize_filter"`
+	FitMode                    string  `yaml:"fit_mode"`
+	PadColor                   string  `yaml:"pad_color"`
 	Format                     string  `yaml:"format"`
+	Lossless                   bool    `yaml:"lossless"`
+	LosslessCover              bool    `yaml:"lossless_cover"`
+	BytesPerPage               int     `yaml:"bytes_per_page"`
+	RawPages                   bool    `yaml:"raw_pages"`
 	AspectRatio                float64 `yaml:"aspect_ratio"`
 	PortraitOnly               bool    `yaml:"portrait_only"`
 	TitlePage                  int     `yaml:"title_page"`
+	VolumeInfoPage             bool    `yaml:"volume_info_page"`
+	BlankPage                  int     `yaml:"-"`
+	Timeout                    int     `yaml:"timeout"`
+	Retries                    int     `yaml:"retries"`
+	Target                     string  `yaml:"target"`
+	OwnerTag                   string  `yaml:"owner_tag"`
+	Language                   string  `yaml:"language"`
+	Publisher                  string  `yaml:"publisher"`
+	Kindlegen                  string  `yaml:"kindlegen"`
+	StatsLog                   string  `yaml:"stats_log"`
+	Opds                       string  `yaml:"opds"`
 
 	// Default Config
 	Show  bool `yaml:"-"`
@@ -60,14 +153,23 @@ type Options struct {
 	BestQuality  bool `yaml:"-"`
 	GreatQuality bool `yaml:"-"`
 	GoodQuality  bool `yaml:"-"`
+	Color        bool `yaml:"-"`
+
+	// where the grayscale and the profile were given, a color profile keeps a grayscale given with or after it
+	GrayscaleOrigin int `yaml:"-"`
+	ProfileOrigin   int `yaml:"-"`
 
 	// Other
-	Workers    int  `yaml:"-"`
-	Dry        bool `yaml:"-"`
-	DryVerbose bool `yaml:"-"`
-	Quiet      bool `yaml:"-"`
-	Version    bool `yaml:"-"`
-	Help       bool `yaml:"-"`
+	Workers      int  `yaml:"-"`
+	Readers      int  `yaml:"-"`
+	Decoders     int  `yaml:"-"`
+	Dry          bool `yaml:"-"`
+	DryVerbose   bool `yaml:"-"`
+	Preflight    bool `yaml:"-"`
+	Quiet        bool `yaml:"-"`
+	ListProfiles bool `yaml:"-"`
+	Version      bool `yaml:"-"`
+	Help         bool `yaml:"-"`
 
 	// Internal
 	profiles profiles.Profiles
@@ -76,21 +178,43 @@ type Options struct {
 // Initialize default options.
 func New() *Options {
 	return &Options{
-		Quality:         85,
-		Grayscale:       true,
-		Crop:            true,
-		CropRatioLeft:   1,
-		CropRatioUp:     1,
-		CropRatioRight:  1,
-		CropRatioBottom: 3,
-		NoBlankImage:    true,
-		HasCover:        true,
-		SortPathMode:    1,
-		ForegroundColor: "000",
-		BackgroundColor: "FFF",
-		Format:          "jpeg",
-		TitlePage:       1,
-		profiles:        profiles.New(),
+		Quality:          85,
+		Grayscale:        true,
+		Crop:             true,
+		CropRatioLeft:    1,
+		CropRatioUp:      1,
+		CropRatioRight:   1,
+		CropRatioBottom:  3,
+		CropThreshold:    0xe0,
+		SpreadMode:       "both",
+		DoublePageRatio:  1,
+		LongStripHeight:  20000,
+		CoverFit:         "fit",
+		RarMode:          "auto",
+		PdfRender:        "auto",
+		PartFormat:       " Part {part} of {total}",
+		SeriesIndex:      -1,
+		NameRegex:        `^(?P<series>.+?)(?:\s+v(?:ol\.?)?\s*(?P<volume>\d+))?(?:\s+c(?:h\.?)?\s*(?P<chapter>\d+))?(?:\s+\((?P<year>\d{4})\))?(?:\s+[\[(].*)?$`,
+		NoBlankImage:     true,
+		AutoContrastClip: 0.5,
+		Gamma:            1,
+		SharpenAmount:    1,
+		DitherLevels:     16,
+		ResizeFilter:     "lanczos",
+		FitMode:          "fit",
+		HasCover:         true,
+		SortPathMode:     1,
+		ForegroundColor:  "000",
+		BackgroundColor:  "FFF",
+		Format:           "jpeg",
+		TitlePage:        1,
+		Timeout:          60,
+		Retries:          3,
+		Language:         "en",
+		Publisher:        "GO Comic Converter",
+		Kindlegen:        "kindlegen",
+		Target:           "generic",
+		profiles:         profiles.New(),
 	}
 }
 
@@ -109,7 +233,12 @@ func (o *Options) String() string {
 		{"Output", o.Output},
 		{"Author", o.Author},
 		{"Title", o.Title},
+		{"Series", o.Series},
+		{"Series Index", o.Volume},
+		{"Panels", o.Panels},
 		{"Workers", o.Workers},
+		{"Readers", o.Readers},
+		{"Decoders", o.Decoders},
 	} {
 		b.WriteString(fmt.Sprintf("\n    %-26s: %v", v.K, v.V))
 	}
@@ -131,11 +260,80 @@ func (o *Options) LoadConfig() error {
 		return nil
 	}
 	defer f.Close()
-	err = yaml.NewDecoder(f).Decode(o)
-	if err != nil && err.Error() != "EOF" {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, o); err != nil {
 		return err
 	}
 
+	settings := map[string]yaml.Node{}
+	if err = yaml.Unmarshal(data, &settings); err == nil {
+		o.setOrigins(settings, OriginConfig)
+	}
+	return nil
+}
+
+// record the origin of the grayscale and of the profile given by these settings
+func (o *Options) setOrigins(settings map[string]yaml.Node, origin int) {
+	if _, ok := settings["grayscale"]; ok {
+		o.GrayscaleOrigin = origin
+	}
+	if _, ok := settings["profile"]; ok {
+		o.ProfileOrigin = origin
+	}
+}
+
+// settings that an input can embed: the images and the layout.
+//
+// the others stay under the control of the user: the paths to other files or programs, the network, the reading
+// of the archives, the identity of the owner and the publisher, the language and the output format (format, target).
+var embeddedSettings = map[string]bool{
+	"name_metadata": true, "name_regex": true, "folder_title": true,
+	"profile": true, "custom_profile": true, "quality": true, "grayscale": true, "grayscale_mode": true,
+	"crop": true, "crop_ratio_left": true, "crop_ratio_up": true, "crop_ratio_right": true, "crop_ratio_bottom": true,
+	"crop_threshold": true, "crop_min_ratio": true, "crop_padding": true, "crop_from_first": true,
+	"deskew": true, "descreen": true, "scaled_decode": true, "brightness": true, "contrast": true,
+	"auto_contrast": true, "auto_contrast_clip": true, "gamma": true, "sharpen": true, "sharpen_amount": true,
+	"dither": true, "dither_levels": true, "auto_rotate": true, "auto_split_double_page": true, "spread_mode": true,
+	"double_page_ratio": true, "long_strip": true, "long_strip_height": true, "no_blank_image": true,
+	"manga": true, "auto_direction": true, "has_cover": true, "no_cover_file": true, "cover_only": true,
+	"cover_fit": true, "cover_ratio": true, "limit_mb": true, "limit_files": true, "part_format": true,
+	"no_zip64": true, "limit_exclude_cover": true, "strip_first_directory_from_toc": true, "min_chapter_pages": true,
+	"toc_thumbnails": true, "sort_path_mode": true, "pdf_dpi": true, "pdf_render": true,
+	"foreground_color": true, "background_color": true, "noresize": true, "box_ratio": true, "resize_filter": true,
+	"fit_mode": true, "pad_color": true, "lossless_cover": true, "bytes_per_page": true, "raw_pages": true,
+	"aspect_ratio": true, "portrait_only": true, "title_page": true, "volume_info_page": true,
+}
+
+// Load the settings embedded in an input, with the same format as the config file.
+//
+// only the embeddedSettings are applied, an archive should not run a program, or write or read outside of it.
+func (o *Options) LoadEmbeddedConfig(data []byte) error {
+	settings := map[string]yaml.Node{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	for k := range settings {
+		if !embeddedSettings[k] {
+			delete(settings, k)
+		}
+	}
+
+	b, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	// nothing is applied on error
+	n := *o
+	d := yaml.NewDecoder(strings.NewReader(string(b)))
+	d.KnownFields(true)
+	if err = d.Decode(&n); err != nil && err != io.EOF {
+		return err
+	}
+	*o = n
+	o.setOrigins(settings, OriginEmbedded)
 	return nil
 }
 
@@ -145,11 +343,12 @@ func (o *Options) ShowConfig() string {
 	profile := o.GetProfile()
 	if profile != nil {
 		profileDesc = fmt.Sprintf(
-			"%s - %s - %dx%d",
-			o.Profile,
+			"%s - %s - %dx%d - %s",
+			profile.Code,
 			profile.Description,
 			profile.Width,
 			profile.Height,
+			profile.Palette(),
 		)
 	}
 
@@ -180,12 +379,19 @@ func (o *Options) ShowConfig() string {
 		titlePage = "when epub is splitted"
 	}
 
+	padColor := o.PadColor
+	if padColor == "" {
+		padColor = "background"
+	}
+
 	grayscaleMode := "normal"
 	switch o.GrayscaleMode {
 	case 1:
 		grayscaleMode = "average"
 	case 2:
-		grayscaleMode = "luminance"
+		grayscaleMode = "luminance (Rec. 709)"
+	case 3:
+		grayscaleMode = "colorblind"
 	}
 
 	var b strings.Builder
@@ -195,28 +401,94 @@ func (o *Options) ShowConfig() string {
 		Condition bool
 	}{
 		{"Profile", profileDesc, true},
+		{"Profiles File", o.ProfilesFile, o.ProfilesFile != ""},
+		{"Name Metadata", o.NameMetadata, true},
+		{"Name Regex", o.NameRegex, o.NameMetadata},
+		{"Folder Title", o.FolderTitle, true},
 		{"Format", o.Format, true},
-		{"Quality", o.Quality, o.Format == "jpeg"},
+		{"Lossless", o.Lossless, o.Format == "webp"},
+		{"Quality", o.Quality, (o.Format == "jpeg" || o.Format == "webp" && !o.Lossless) && o.Qualities == ""},
+		{"Qualities", o.Qualities, o.Format == "jpeg" && o.Qualities != ""},
+		{"Lossless Cover", o.LosslessCover, o.Format == "jpeg" || o.Format == "webp" && !o.Lossless},
+		{"Raw Pages", o.RawPages, o.Format == "jpeg"},
+		{"Bytes Per Page", fmt.Sprintf("%d bytes", o.BytesPerPage), (o.Format == "jpeg" || o.Format == "webp" && !o.Lossless) && o.BytesPerPage > 0},
 		{"Grayscale", o.Grayscale, true},
 		{"Grayscale Mode", grayscaleMode, o.Grayscale},
 		{"Crop", o.Crop, true},
 		{"CropRatio", fmt.Sprintf("%d Left - %d Up - %d Right - %d Bottom", o.CropRatioLeft, o.CropRatioUp, o.CropRatioRight, o.CropRatioBottom), o.Crop},
+		{"Crop Threshold", o.CropThreshold, o.Crop || o.NoBlankImage},
+		{"Crop Min Ratio", o.CropMinRatio, o.Crop && o.CropMinRatio > 0},
+		{"Crop Padding", fmt.Sprintf("%d pixels", o.CropPadding), o.Crop && o.CropPadding > 0},
+		{"Crop From First", o.CropFromFirst, o.Crop},
+		{"Deskew", o.Deskew, true},
+		{"Descreen", o.Descreen, true},
+		{"Scaled Decode", o.ScaledDecode, o.ScaledDecode},
 		{"Brightness", o.Brightness, o.Brightness != 0},
 		{"Contrast", o.Contrast, o.Contrast != 0},
+		{"Auto Contrast", o.AutoContrast, true},
+		{"Auto Contrast Clip", fmt.Sprintf("%.1f%%", o.AutoContrastClip), o.AutoContrast},
+		{"Gamma", o.Gamma, o.Gamma != 1},
+		{"Sharpen", o.Sharpen, true},
+		{"Sharpen Amount", o.SharpenAmount, o.Sharpen},
+		{"Dither", o.Dither, o.Grayscale},
+		{"Dither Levels", o.DitherLevels, o.Grayscale && o.Dither},
 		{"AutoRotate", o.AutoRotate, true},
 		{"AutoSplitDoublePage", o.AutoSplitDoublePage, true},
+		{"Spread Mode", o.SpreadMode, o.AutoSplitDoublePage},
+		{"Double Page Ratio", o.DoublePageRatio, o.DoublePageRatio != 1},
+		{"Long Strip", o.LongStrip, true},
+		{"Long Strip Height", o.LongStripHeight, o.LongStrip},
 		{"NoBlankImage", o.NoBlankImage, true},
 		{"Manga", o.Manga, true},
+		{"Auto Direction", o.AutoDirection, true},
 		{"HasCover", o.HasCover, true},
+		{"NoCoverFile", o.NoCoverFile, o.HasCover},
+		{"Cover", o.Cover, o.HasCover && o.Cover != ""},
+		{"Cover Only", o.CoverOnly, o.HasCover && o.Cover != ""},
+		{"Cover Fit", o.CoverFit, o.HasCover},
+		{"Cover Ratio", o.CoverRatio, o.HasCover && o.CoverRatio != ""},
+		{"Cover Back", fmt.Sprintf("page %d", o.CoverBack), o.HasCover && o.CoverBack != 0},
 		{"LimitMb", fmt.Sprintf("%d Mb", o.LimitMb), o.LimitMb != 0},
+		{"LimitFiles", fmt.Sprintf("%d images", o.LimitFiles), o.LimitFiles != 0},
+		{"PartFormat", fmt.Sprintf("%q", o.PartFormat), o.LimitMb > 0 || o.LimitFiles > 0},
+		{"NoZip64", o.NoZip64, o.NoZip64},
+		{"LimitExcludeCover", o.LimitExcludeCover, o.LimitMb > 0},
 		{"StripFirstDirectoryFromToc", o.StripFirstDirectoryFromToc, true},
+		{"MinChapterPages", o.MinChapterPages, o.MinChapterPages > 0},
+		{"Toc Thumbnails", o.TocThumbnails, true},
 		{"SortPathMode", sortpathmode, true},
+		{"Reverse Order", o.ReverseOrder, o.ReverseOrder},
+		{"IncludeHidden", o.IncludeHidden, true},
+		{"Warn Unsupported", o.WarnUnsupported, true},
+		{"Sniff Content", o.SniffContent, true},
+		{"Rar Mode", o.RarMode, true},
+		{"Check Archive", o.CheckArchive, true},
+		{"Skip Bad", o.SkipBad, true},
+		{"No Embedded Settings", o.NoEmbeddedSettings, true},
+		{"Pdf Dpi", o.PdfDpi, o.PdfDpi > 0},
+		{"Pdf Render", o.PdfRender, true},
+		{"Sample Pages", o.SamplePages, o.SamplePages > 0},
 		{"Foreground Color", fmt.Sprintf("#%s", o.ForegroundColor), true},
 		{"Background Color", fmt.Sprintf("#%s", o.BackgroundColor), true},
 		{"Resize", !o.NoResize, true},
+		{"Resize Filter", o.ResizeFilter, !o.NoResize},
+		{"Box Ratio", o.BoxRatio, !o.NoResize && o.BoxRatio > 0},
+		{"Fit Mode", o.FitMode, !o.NoResize},
+		{"Pad Color", padColor, !o.NoResize && o.FitMode == "contain"},
 		{"Aspect Ratio", aspectRatio, true},
 		{"Portrait Only", o.PortraitOnly, true},
 		{"Title Page", titlePage, true},
+		{"Volume Info Page", o.VolumeInfoPage, o.LimitMb > 0 || o.LimitFiles > 0},
+		{"Blank Page", fmt.Sprintf("before page %d", o.BlankPage), o.BlankPage > 0 && !o.PortraitOnly},
+		{"Timeout", fmt.Sprintf("%ds", o.Timeout), o.Timeout != 0},
+		{"Retries", o.Retries, true},
+		{"Target", o.Target, true},
+		{"Owner Tag", o.OwnerTag, o.OwnerTag != ""},
+		{"Language", o.Language, true},
+		{"Publisher", o.Publisher, true},
+		{"Kindlegen", o.Kindlegen, o.Kindlegen != "kindlegen"},
+		{"Stats Log", o.StatsLog, o.StatsLog != ""},
+		{"Opds", o.Opds, o.Opds != ""},
 	} {
 		if v.Condition {
 			b.WriteString(fmt.Sprintf("\n    %-26s: %v", v.Key, v.Value))
@@ -241,8 +513,71 @@ func (o *Options) SaveConfig() error {
 	return yaml.NewEncoder(f).Encode(o)
 }
 
+// add the profiles from the profiles file
+func (o *Options) LoadProfiles() error {
+	if o.ProfilesFile == "" {
+		return nil
+	}
+	p, err := o.profiles.Load(o.ProfilesFile)
+	if err != nil {
+		return err
+	}
+	o.profiles = p
+	return nil
+}
+
+// ranges of the keep pages like 10-50,60,80-: first and last page, last = 0 until the end
+func (o *Options) GetKeepPages() [][2]int {
+	ranges := make([][2]int, 0)
+	if o.KeepPages == "" {
+		return ranges
+	}
+	for _, s := range strings.Split(o.KeepPages, ",") {
+		first, last, isRange := strings.Cut(s, "-")
+		var r [2]int
+		r[0], _ = strconv.Atoi(first)
+		r[1] = r[0]
+		if isRange {
+			r[1], _ = strconv.Atoi(last)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// list of the qualities to compare, empty if not set
+func (o *Options) GetQualities() []int {
+	qualities := make([]int, 0)
+	if o.Qualities == "" {
+		return qualities
+	}
+	for _, s := range strings.Split(o.Qualities, ",") {
+		q, _ := strconv.Atoi(s)
+		qualities = append(qualities, q)
+	}
+	return qualities
+}
+
+// aspect ratio (height/width) of the cover ratio W:H, 0 if not set
+func (o *Options) GetCoverRatio() float64 {
+	var w, h int
+	if _, err := fmt.Sscanf(o.CoverRatio, "%d:%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+		return 0
+	}
+	return float64(h) / float64(w)
+}
+
 // shortcut to get current profile
+//
+// the custom profile WxH take precedence over the profile code.
 func (o *Options) GetProfile() *profiles.Profile {
+	if o.CustomProfile != "" {
+		var w, h int
+		if _, err := fmt.Sscanf(o.CustomProfile, "%dx%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+			return nil
+		}
+		return &profiles.Profile{Code: "Custom", Description: "Custom profile", Width: w, Height: h}
+	}
 	return o.profiles.Get(o.Profile)
 }
 
@@ -250,3 +585,8 @@ func (o *Options) GetProfile() *profiles.Profile {
 func (o *Options) AvailableProfiles() string {
 	return o.profiles.String()
 }
+
+// all available profiles grouped by manufacturer
+func (o *Options) ListProfilesByGroup() string {
+	return o.profiles.Table()
+}
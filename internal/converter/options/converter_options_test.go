@@ -0,0 +1,46 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigOrigins(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".go-comic-converter.yaml"), []byte("profile: KoLC\ngrayscale: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := New()
+	if err := o.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if o.Profile != "KoLC" || !o.Grayscale {
+		t.Errorf("profile %q grayscale %v, want KoLC and true", o.Profile, o.Grayscale)
+	}
+	if o.ProfileOrigin != OriginConfig || o.GrayscaleOrigin != OriginConfig {
+		t.Errorf("origins profile %d grayscale %d, want %d", o.ProfileOrigin, o.GrayscaleOrigin, OriginConfig)
+	}
+}
+
+func TestLoadEmbeddedConfigOrigins(t *testing.T) {
+	o := New()
+	o.ProfileOrigin, o.GrayscaleOrigin = OriginConfig, OriginConfig
+
+	if err := o.LoadEmbeddedConfig([]byte("profile: KoLC\n")); err != nil {
+		t.Fatal(err)
+	}
+	if o.ProfileOrigin != OriginEmbedded || o.GrayscaleOrigin != OriginConfig {
+		t.Errorf("origins profile %d grayscale %d, want %d and %d", o.ProfileOrigin, o.GrayscaleOrigin, OriginEmbedded, OriginConfig)
+	}
+
+	// the settings not embeddable are ignored
+	if err := o.LoadEmbeddedConfig([]byte("grayscale: false\nkindlegen: /bin/true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if o.GrayscaleOrigin != OriginEmbedded || o.Kindlegen == "/bin/true" {
+		t.Errorf("grayscale origin %d kindlegen %q, want %d and not embedded", o.GrayscaleOrigin, o.Kindlegen, OriginEmbedded)
+	}
+}
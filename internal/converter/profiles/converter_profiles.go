@@ -4,7 +4,10 @@ Manage supported profiles for go-comic-converter.
 package profiles
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 )
 
@@ -13,6 +16,7 @@ type Profile struct {
 	Description string
 	Width       int
 	Height      int
+	Color       bool // color eInk, the pages keep their colors by default
 }
 
 type Profiles []Profile
@@ -20,45 +24,94 @@ type Profiles []Profile
 // Initialize list of all supported profiles.
 func New() Profiles {
 	return []Profile{
-		{"K1", "Kindle 1", 600, 670},
-		{"K11", "Kindle 11", 1072, 1448},
-		{"K2", "Kindle 2", 600, 670},
-		{"K34", "Kindle Keyboard/Touch", 600, 800},
-		{"K578", "Kindle", 600, 800},
-		{"KDX", "Kindle DX/DXG", 824, 1000},
-		{"KPW", "Kindle Paperwhite 1/2", 758, 1024},
-		{"KV", "Kindle Paperwhite 3/4/Voyage/Oasis", 1072, 1448},
-		{"KPW5", "Kindle Paperwhite 5/Signature Edition", 1236, 1648},
-		{"KO", "Kindle Oasis 2/3", 1264, 1680},
-		{"KS", "Kindle Scribe", 1860, 2480},
+		{"K1", "Kindle 1", 600, 670, false},
+		{"K11", "Kindle 11", 1072, 1448, false},
+		{"K2", "Kindle 2", 600, 670, false},
+		{"K34", "Kindle Keyboard/Touch", 600, 800, false},
+		{"K578", "Kindle", 600, 800, false},
+		{"KDX", "Kindle DX/DXG", 824, 1000, false},
+		{"KPW", "Kindle Paperwhite 1/2", 758, 1024, false},
+		{"KV", "Kindle Paperwhite 3/4/Voyage/Oasis", 1072, 1448, false},
+		{"KPW5", "Kindle Paperwhite 5/Signature Edition", 1236, 1648, false},
+		{"KO", "Kindle Oasis 2/3", 1264, 1680, false},
+		{"KS", "Kindle Scribe", 1860, 2480, false},
+		{"KCS", "Kindle Colorsoft", 1264, 1680, true},
 		// Kobo
-		{"KoMT", "Kobo Mini/Touch", 600, 800},
-		{"KoG", "Kobo Glo", 768, 1024},
-		{"KoGHD", "Kobo Glo HD", 1072, 1448},
-		{"KoA", "Kobo Aura", 758, 1024},
-		{"KoAHD", "Kobo Aura HD", 1080, 1440},
-		{"KoAH2O", "Kobo Aura H2O", 1080, 1430},
-		{"KoAO", "Kobo Aura ONE", 1404, 1872},
-		{"KoN", "Kobo Nia", 758, 1024},
-		{"KoC", "Kobo Clara HD/Kobo Clara 2E", 1072, 1448},
-		{"KoL", "Kobo Libra H2O/Kobo Libra 2", 1264, 1680},
-		{"KoF", "Kobo Forma", 1440, 1920},
-		{"KoS", "Kobo Sage", 1440, 1920},
-		{"KoE", "Kobo Elipsa", 1404, 1872},
+		{"KoMT", "Kobo Mini/Touch", 600, 800, false},
+		{"KoG", "Kobo Glo", 768, 1024, false},
+		{"KoGHD", "Kobo Glo HD", 1072, 1448, false},
+		{"KoA", "Kobo Aura", 758, 1024, false},
+		{"KoAHD", "Kobo Aura HD", 1080, 1440, false},
+		{"KoAH2O", "Kobo Aura H2O", 1080, 1430, false},
+		{"KoAO", "Kobo Aura ONE", 1404, 1872, false},
+		{"KoN", "Kobo Nia", 758, 1024, false},
+		{"KoC", "Kobo Clara HD/Kobo Clara 2E", 1072, 1448, false},
+		{"KoL", "Kobo Libra H2O/Kobo Libra 2", 1264, 1680, false},
+		{"KoF", "Kobo Forma", 1440, 1920, false},
+		{"KoS", "Kobo Sage", 1440, 1920, false},
+		{"KoE", "Kobo Elipsa", 1404, 1872, false},
+		{"KoCC", "Kobo Clara Colour", 1072, 1448, true},
+		{"KoLC", "Kobo Libra Colour", 1264, 1680, true},
 		// High Resolution for Tablette
-		{"HR", "High Resolution", 2400, 3840},
+		{"HR", "High Resolution", 2400, 3840, false},
 	}
 }
 
 func (p Profiles) String() string {
 	s := make([]string, 0)
 	for _, v := range p {
-		s = append(s, fmt.Sprintf(
-			"    - %-7s ( %9s ) - %s",
-			v.Code,
-			fmt.Sprintf("%dx%d", v.Width, v.Height),
-			v.Description,
-		))
+		s = append(s, v.String())
+	}
+	return strings.Join(s, "\n")
+}
+
+func (v Profile) String() string {
+	description := v.Description
+	if v.Color {
+		description += " (color)"
+	}
+	return fmt.Sprintf(
+		"    - %-7s ( %9s ) - %s",
+		v.Code,
+		fmt.Sprintf("%dx%d", v.Width, v.Height),
+		description,
+	)
+}
+
+// palette of the device
+func (v Profile) Palette() string {
+	if v.Color {
+		return "color"
+	}
+	return "grayscale"
+}
+
+// manufacturer of the device, from the start of the description
+func (v Profile) Group() string {
+	for _, g := range []string{"Kindle", "Kobo"} {
+		if strings.HasPrefix(v.Description, g) {
+			return g
+		}
+	}
+	return "Other"
+}
+
+// List the profiles grouped by manufacturer, in the order of the list.
+func (p Profiles) Table() string {
+	groups := make([]string, 0)
+	rows := make(map[string][]string)
+	for _, v := range p {
+		g := v.Group()
+		if _, ok := rows[g]; !ok {
+			groups = append(groups, g)
+		}
+		rows[g] = append(rows[g], v.String())
+	}
+
+	s := make([]string, 0)
+	for _, g := range groups {
+		s = append(s, g+":")
+		s = append(s, rows[g]...)
 	}
 	return strings.Join(s, "\n")
 }
@@ -72,3 +125,33 @@ func (p Profiles) Get(name string) *Profile {
 	}
 	return nil
 }
+
+// Load additional profiles from a json file.
+//
+// The file is a list of profiles: [{"code": "X", "description": "My device", "width": 1000, "height": 1400, "color": false}]
+func (p Profiles) Load(filename string) (Profiles, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	custom := []Profile{}
+	if err := json.NewDecoder(f).Decode(&custom); err != nil {
+		return nil, fmt.Errorf("invalid profiles file: %w", err)
+	}
+
+	for _, c := range custom {
+		if c.Code == "" {
+			return nil, errors.New("profile code missing")
+		}
+		if c.Width <= 0 || c.Height <= 0 {
+			return nil, fmt.Errorf("profile %q should have a width and height > 0", c.Code)
+		}
+		if p.Get(c.Code) != nil {
+			return nil, fmt.Errorf("profile %q already exists", c.Code)
+		}
+		p = append(p, c)
+	}
+	return p, nil
+}
@@ -5,7 +5,9 @@ package epub
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"fmt"
+	"image"
 	"math"
 	"os"
 	"path/filepath"
@@ -23,18 +25,27 @@ import (
 	epubtree "github.com/celogeek/go-comic-converter/v2/internal/epub/tree"
 	epubzip "github.com/celogeek/go-comic-converter/v2/internal/epub/zip"
 	"github.com/gofrs/uuid"
+	"github.com/schollz/progressbar/v3"
 )
 
 type ePub struct {
 	*epuboptions.Options
 	UID       string
-	Publisher string
 	UpdatedAt string
+	Stats     Stats
 
 	templateProcessor *template.Template
 	imageProcessor    *epubimageprocessor.EPUBImageProcessor
 }
 
+// summary of the conversion, filled by Write
+type Stats struct {
+	Outputs []string
+	Pages   int
+	Skipped int
+	Cover   image.Image
+}
+
 type epubPart struct {
 	Cover  *epubimage.Image
 	Images []*epubimage.Image
@@ -43,7 +54,10 @@ type epubPart struct {
 
 // initialize EPUB
 func New(options *epuboptions.Options) *ePub {
-	uid := uuid.Must(uuid.NewV4())
+	uid := options.Identifier
+	if uid == "" {
+		uid = fmt.Sprintf("urn:uuid:%s", uuid.Must(uuid.NewV4()))
+	}
 	tmpl := template.New("parser")
 	tmpl.Funcs(template.FuncMap{
 		"mod":  func(i, j int) bool { return i%j == 0 },
@@ -52,8 +66,7 @@ func New(options *epuboptions.Options) *ePub {
 
 	return &ePub{
 		Options:           options,
-		UID:               uid.String(),
-		Publisher:         "GO Comic Converter",
+		UID:               uid,
 		UpdatedAt:         time.Now().UTC().Format("2006-01-02T15:04:05Z"),
 		templateProcessor: tmpl,
 		imageProcessor:    epubimageprocessor.New(options),
@@ -79,6 +92,7 @@ func (e *ePub) writeImage(wz *epubzip.EPUBZip, img *epubimage.Image, zipImg *zip
 			"ViewPort":   fmt.Sprintf("width=%d,height=%d", e.Image.View.Width, e.Image.View.Height),
 			"ImagePath":  img.ImgPath(),
 			"ImageStyle": img.ImgStyle(e.Image.View.Width, e.Image.View.Height, ""),
+			"Kobo":       e.Target == "kobo",
 		})),
 	)
 	if err == nil {
@@ -99,6 +113,69 @@ func (e *ePub) writeBlank(wz *epubzip.EPUBZip, img *epubimage.Image) error {
 	)
 }
 
+// write the blank page used to realign the spreads
+func (e *ePub) writePadding(wz *epubzip.EPUBZip) error {
+	return wz.WriteContent(
+		"OEBPS/Text/blank.xhtml",
+		[]byte(e.render(epubtemplates.Blank, map[string]any{
+			"Title":    "Blank Page",
+			"ViewPort": fmt.Sprintf("width=%d,height=%d", e.Image.View.Width, e.Image.View.Height),
+		})),
+	)
+}
+
+// write the page describing the volume, with the range of source pages it contains
+func (e *ePub) writeInfoPage(wz *epubzip.EPUBZip, title string, part []*epubimage.Image, current, total int) error {
+	return wz.WriteContent(
+		"OEBPS/Text/info.xhtml",
+		[]byte(e.render(epubtemplates.Info, map[string]any{
+			"Title":     title,
+			"ViewPort":  fmt.Sprintf("width=%d,height=%d", e.Image.View.Width, e.Image.View.Height),
+			"Current":   current,
+			"Total":     total,
+			"FirstPage": part[0].Id + 1,
+			"LastPage":  part[len(part)-1].Id + 1,
+		})),
+	)
+}
+
+// write the thumbnails of the first page of each chapter
+func (e *ePub) writeThumbnails(wz *epubzip.EPUBZip, imgStorage *epubzip.EPUBZipStorageImageReader, images []*epubimage.Image, thumbnails map[*epubimage.Image]bool) error {
+	for _, img := range images {
+		if !thumbnails[img] {
+			continue
+		}
+		thumb, err := e.imageProcessor.Thumbnail(imgStorage.Get(img.EPUBImgPath()), img.EPUBThumbPath())
+		if err != nil {
+			return err
+		}
+		if err := wz.WriteRaw(thumb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mark the page that need a blank page before it to realign the spreads
+func (e *ePub) markBlankPage(epubParts []*epubPart) {
+	if e.BlankPage == 0 || e.Image.View.PortraitOnly {
+		return
+	}
+	n := 0
+	for _, p := range epubParts {
+		for _, img := range p.Images {
+			if img.Part != 0 {
+				continue
+			}
+			n++
+			if n == e.BlankPage {
+				img.BlankBefore = true
+				return
+			}
+		}
+	}
+}
+
 // write title image
 func (e *ePub) writeCoverImage(wz *epubzip.EPUBZip, img *epubimage.Image, part, totalParts int) error {
 	title := "Cover"
@@ -113,7 +190,7 @@ func (e *ePub) writeCoverImage(wz *epubzip.EPUBZip, img *epubimage.Image, part,
 		[]byte(e.render(epubtemplates.Text, map[string]any{
 			"Title":      title,
 			"ViewPort":   fmt.Sprintf("width=%d,height=%d", e.Image.View.Width, e.Image.View.Height),
-			"ImagePath":  fmt.Sprintf("Images/cover.%s", e.Image.Format),
+			"ImagePath":  fmt.Sprintf("Images/cover.%s", e.Image.CoverFormat()),
 			"ImageStyle": img.ImgStyle(e.Image.View.Width, e.Image.View.Height, ""),
 		})),
 	); err != nil {
@@ -233,19 +310,41 @@ func (e *ePub) getParts() (parts []*epubPart, imgStorage *epubzip.EPUBZipStorage
 		return nil, nil, err
 	}
 
-	// compute size of the EPUB part and try to be as close as possible of the target
+	return e.splitParts(cover, images, imgStorage.Size), imgStorage, nil
+}
+
+// split the pages into parts under the limits, size gives the size of a stored image by its path.
+//
+// compute size of the EPUB part and try to be as close as possible of the target
+// a new part starts as soon as one of the limits is reached
+func (e *ePub) splitParts(cover *epubimage.Image, images []*epubimage.Image, size func(filename string) uint64) (parts []*epubPart) {
 	maxSize := uint64(e.LimitMb * 1024 * 1024)
+	maxImages := 0
+	if e.NoZip64 {
+		// keep a margin for the central directory
+		if limit := uint64(epubzip.MaxSizeWithoutZip64 - 16*1024*1024); maxSize == 0 || maxSize > limit {
+			maxSize = limit
+		}
+		// an image and its page for each entry, the descriptor files are included in the margin
+		maxImages = (epubzip.MaxEntriesWithoutZip64 - 32) / 2
+	}
 	xhtmlSize := uint64(1024)
 	// descriptor files + title + cover
-	baseSize := uint64(16*1024) + imgStorage.Size(cover.EPUBImgPath())*2
+	baseSize := uint64(16 * 1024)
+	if !e.LimitExcludeCover {
+		baseSize += size(cover.EPUBImgPath()) * 2
+	}
 
 	currentSize := baseSize
 	currentImages := make([]*epubimage.Image, 0)
 	part := 1
 
 	for _, img := range images {
-		imgSize := imgStorage.Size(img.EPUBImgPath()) + xhtmlSize
-		if maxSize > 0 && len(currentImages) > 0 && currentSize+imgSize > maxSize {
+		imgSize := size(img.EPUBImgPath()) + xhtmlSize
+		sizeReached := maxSize > 0 && currentSize+imgSize > maxSize
+		imagesReached := (maxImages > 0 && len(currentImages) >= maxImages) ||
+			(e.LimitFiles > 0 && e.partImages(append(currentImages, img)) > e.LimitFiles)
+		if len(currentImages) > 0 && (sizeReached || imagesReached) {
 			parts = append(parts, &epubPart{
 				Cover:  cover,
 				Images: currentImages,
@@ -264,7 +363,30 @@ func (e *ePub) getParts() (parts []*epubPart, imgStorage *epubzip.EPUBZipStorage
 		})
 	}
 
-	return parts, imgStorage, nil
+	return parts
+}
+
+// number of images written in a part with these pages, the cover, the title and the thumbnails included.
+//
+// the limit is reached only when the book has several parts, so their title page is counted.
+// the volume info page and the blank pages have no image.
+func (e *ePub) partImages(images []*epubimage.Image) int {
+	n := len(images)
+	if strings.EqualFold(filepath.Ext(e.Output), ".cbz") {
+		if e.Image.HasCover {
+			n++
+		}
+		return n
+	}
+
+	n++ // cover
+	if e.TitlePage > 0 {
+		n++
+	}
+	if e.TocThumbnails {
+		n += len(epubtemplates.ChapterStarts(images, e.MinChapterPages))
+	}
+	return n
 }
 
 // create a tree from the directories.
@@ -287,6 +409,47 @@ func (e *ePub) getTree(images []*epubimage.Image, skip_files bool) string {
 	return c.WriteString("")
 }
 
+// load the panels description used by the region based navigation
+func (e *ePub) loadPanels() (map[string][][]float64, error) {
+	if e.Panels == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(e.Panels)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	panels := map[string][][]float64{}
+	if err := json.NewDecoder(f).Decode(&panels); err != nil {
+		return nil, fmt.Errorf("invalid panels file: %w", err)
+	}
+	for name, boxes := range panels {
+		for _, b := range boxes {
+			if len(b) != 4 {
+				return nil, fmt.Errorf("panels of %q should be x, y, w, h", name)
+			}
+			for _, v := range b {
+				if v < 0 || v > 100 {
+					return nil, fmt.Errorf("panels of %q should be in percent", name)
+				}
+			}
+		}
+	}
+	return panels, nil
+}
+
+// check if one of the images have panels
+func (e *ePub) hasPanels(panels map[string][][]float64, images []*epubimage.Image) bool {
+	for _, img := range images {
+		if img.Part == 0 && len(panels[filepath.ToSlash(filepath.Join(img.Path, img.Name))]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *ePub) computeAspectRatio(epubParts []*epubPart) float64 {
 	var (
 		bestAspectRatio      float64
@@ -298,11 +461,12 @@ func (e *ePub) computeAspectRatio(epubParts []*epubPart) float64 {
 		return float64(math.Round(v*10000)) / 10000
 	}
 
+	images := []*epubimage.Image{epubParts[0].Cover}
 	for _, p := range epubParts {
-		aspectRatio[trunc(p.Cover.OriginalAspectRatio)]++
-		for _, i := range p.Images {
-			aspectRatio[trunc(i.OriginalAspectRatio)]++
-		}
+		images = append(images, p.Images...)
+	}
+	for _, i := range epubimage.Sample(len(images), e.SamplePages) {
+		aspectRatio[trunc(images[i].OriginalAspectRatio)]++
 	}
 
 	for k, v := range aspectRatio {
@@ -333,14 +497,77 @@ func (e *ePub) computeViewPort(epubParts []*epubPart) {
 	}
 }
 
+// report the health of the input
+func (e *ePub) preflight() error {
+	r, err := e.imageProcessor.Inspect()
+	if err != nil {
+		return err
+	}
+
+	dimensions := make([]string, 0, len(r.Dimensions))
+	for d := range r.Dimensions {
+		dimensions = append(dimensions, d)
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		a, b := r.Dimensions[dimensions[i]], r.Dimensions[dimensions[j]]
+		if a == b {
+			return dimensions[i] < dimensions[j]
+		}
+		return a > b
+	})
+
+	fmt.Fprintf(os.Stderr, "Pages: %d\n", r.Pages)
+	fmt.Fprintln(os.Stderr, "Dimensions:")
+	for _, d := range dimensions {
+		fmt.Fprintf(os.Stderr, "  - %-11s: %d\n", d, r.Dimensions[d])
+	}
+	fmt.Fprintf(os.Stderr, "Estimated size: %d Mb\n", r.EstimatedSize/1024/1024)
+
+	if len(r.Unreadable) > 0 {
+		names := make([]string, 0, len(r.Unreadable))
+		for name := range r.Unreadable {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(os.Stderr, "Unreadable:")
+		for _, name := range names {
+			fmt.Fprintf(os.Stderr, "  - %s: %s\n", name, r.Unreadable[name])
+		}
+		return fmt.Errorf("%d unreadable images", len(r.Unreadable))
+	}
+
+	return nil
+}
+
 // create the zip
 func (e *ePub) Write() error {
-	type zipContent struct {
-		Name    string
-		Content string
+	if e.CheckArchive {
+		if err := e.imageProcessor.VerifyArchive(); err != nil {
+			return err
+		}
+	}
+
+	if e.Preflight {
+		return e.preflight()
+	}
+
+	panels, err := e.loadPanels()
+	if err != nil {
+		return err
+	}
+
+	// next to the output to stay on the same disk
+	if !e.Dry {
+		tmpDir, err := os.MkdirTemp(filepath.Dir(e.Output), ".go-comic-converter-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		e.TmpDir = tmpDir
 	}
 
 	epubParts, imgStorage, err := e.getParts()
+	e.Stats.Skipped = e.imageProcessor.Skipped()
 	if err != nil {
 		return err
 	}
@@ -360,11 +587,22 @@ func (e *ePub) Write() error {
 		imgStorage.Close()
 		imgStorage.Remove()
 	}()
+	if e.Image.HasCover {
+		e.Stats.Cover = epubParts[0].Cover.Raw
+		e.Stats.Pages++
+	}
+	// counted once, the outputs of each quality have the same pages
+	for _, part := range epubParts {
+		e.Stats.Pages += len(part.Images)
+	}
 
-	totalParts := len(epubParts)
+	qualities := e.Image.Qualities
+	if len(qualities) == 0 {
+		qualities = []int{e.Image.Quality}
+	}
 
 	bar := epubprogress.New(epubprogress.Options{
-		Max:         totalParts,
+		Max:         len(epubParts) * len(qualities),
 		Description: "Writing Part",
 		CurrentJob:  2,
 		TotalJob:    2,
@@ -372,86 +610,218 @@ func (e *ePub) Write() error {
 	})
 
 	e.computeViewPort(epubParts)
-	hasTitlePage := e.TitlePage == 1 || (e.TitlePage == 2 && totalParts > 1)
+	e.markBlankPage(epubParts)
+	w := e.writer(panels)
+
+	if len(e.Image.Qualities) == 0 {
+		if err := e.writeParts(e.Output, epubParts, imgStorage, w, bar); err != nil {
+			return err
+		}
+		bar.Close()
+		fmt.Fprintln(os.Stderr)
+		return nil
+	}
+
+	// the same pages encoded with each quality, the first one is converted with the pages
+	quality := e.Image.Quality
+	defer func() { e.Image.Quality = quality }()
+	for i, q := range qualities {
+		storage := imgStorage
+		if i > 0 {
+			if storage, err = epubzip.NewEPUBZipStorageImageReader(e.QualityImgStorage(q)); err != nil {
+				return err
+			}
+		}
+		e.Image.Quality = q
+		base, ext := splitOutputExt(e.Output)
+		err := e.writeParts(fmt.Sprintf("%s.q%d%s", base, q, ext), epubParts, storage, w, bar)
+		if i > 0 {
+			storage.Close()
+			storage.Remove()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	bar.Close()
+	fmt.Fprintln(os.Stderr)
+
+	for _, output := range e.Stats.Outputs {
+		if fi, err := os.Stat(output); err == nil {
+			fmt.Fprintf(os.Stderr, "%s: %.1f Mb\n", output, float64(fi.Size())/1024/1024)
+		}
+	}
+
+	return nil
+}
+
+// name of the output without the extension, .kepub.epub is kept as one extension
+func splitOutputExt(output string) (base string, ext string) {
+	ext = filepath.Ext(output)
+	if strings.HasSuffix(output, ".kepub.epub") {
+		ext = ".kepub.epub"
+	}
+	return output[0 : len(output)-len(ext)], ext
+}
+
+// write the parts of the output, each part in its own file
+func (e *ePub) writeParts(output string, epubParts []*epubPart, imgStorage *epubzip.EPUBZipStorageImageReader, w partWriter, bar *progressbar.ProgressBar) error {
+	totalParts := len(epubParts)
 	for i, part := range epubParts {
-		ext := filepath.Ext(e.Output)
+		base, ext := splitOutputExt(output)
 		suffix := ""
 		if totalParts > 1 {
 			fmtLen := len(fmt.Sprint(totalParts))
-			fmtPart := fmt.Sprintf(" Part %%0%dd of %%0%dd", fmtLen, fmtLen)
-			suffix = fmt.Sprintf(fmtPart, i+1, totalParts)
+			suffix = strings.NewReplacer(
+				"{part}", fmt.Sprintf("%0*d", fmtLen, i+1),
+				"{total}", fmt.Sprintf("%0*d", fmtLen, totalParts),
+			).Replace(e.PartFormat)
 		}
 
-		path := fmt.Sprintf("%s%s%s", e.Output[0:len(e.Output)-len(ext)], suffix, ext)
+		path := fmt.Sprintf("%s%s%s", base, suffix, ext)
 		wz, err := epubzip.New(path)
 		if err != nil {
 			return err
 		}
 		defer wz.Close()
+		e.Stats.Outputs = append(e.Stats.Outputs, path)
 
 		title := e.Title
 		if totalParts > 1 {
 			title = fmt.Sprintf("%s [%d/%d]", title, i+1, totalParts)
 		}
+		if err := w.writePart(wz, title, part, i+1, totalParts, imgStorage); err != nil {
+			return err
+		}
+		bar.Add(1)
+	}
 
-		content := []zipContent{
-			{"META-INF/container.xml", epubtemplates.Container},
-			{"META-INF/com.apple.ibooks.display-options.xml", epubtemplates.AppleBooks},
-			{"OEBPS/content.opf", epubtemplates.Content(&epubtemplates.ContentOptions{
-				Title:        title,
-				HasTitlePage: hasTitlePage,
-				UID:          e.UID,
-				Author:       e.Author,
-				Publisher:    e.Publisher,
-				UpdatedAt:    e.UpdatedAt,
-				ImageOptions: e.Image,
-				Cover:        part.Cover,
-				Images:       part.Images,
-				Current:      i + 1,
-				Total:        totalParts,
-			})},
-			{"OEBPS/toc.xhtml", epubtemplates.Toc(title, hasTitlePage, e.StripFirstDirectoryFromToc, part.Images)},
-			{"OEBPS/Text/style.css", e.render(epubtemplates.Style, map[string]any{
-				"View": e.Image.View,
-			})},
-		}
-
-		if err = wz.WriteMagic(); err != nil {
+	return nil
+}
+
+// writer of the content of a part, in the format of the output
+type partWriter interface {
+	writePart(wz *epubzip.EPUBZip, title string, part *epubPart, current, total int, imgStorage *epubzip.EPUBZipStorageImageReader) error
+}
+
+// writer of the output, a cbz if the output ends with .cbz, an EPUB otherwise
+func (e *ePub) writer(panels map[string][][]float64) partWriter {
+	if strings.EqualFold(filepath.Ext(e.Output), ".cbz") {
+		return &cbzWriter{e}
+	}
+	return &epubWriter{e, panels}
+}
+
+// write the parts as EPUB, with their pages, toc and metadata
+type epubWriter struct {
+	*ePub
+	panels map[string][][]float64
+}
+
+func (e *epubWriter) writePart(wz *epubzip.EPUBZip, title string, part *epubPart, current, total int, imgStorage *epubzip.EPUBZipStorageImageReader) error {
+	type zipContent struct {
+		Name    string
+		Content string
+	}
+
+	hasTitlePage := e.TitlePage == 1 || (e.TitlePage == 2 && total > 1)
+	hasInfoPage := e.VolumeInfoPage && total > 1
+	hasRegions := e.hasPanels(e.panels, part.Images)
+	thumbnails := map[*epubimage.Image]bool{}
+	if e.TocThumbnails {
+		for _, img := range epubtemplates.ChapterStarts(part.Images, e.MinChapterPages) {
+			thumbnails[img] = true
+		}
+	}
+
+	content := []zipContent{
+		{"META-INF/container.xml", epubtemplates.Container},
+		{"META-INF/com.apple.ibooks.display-options.xml", e.render(epubtemplates.AppleBooks, map[string]any{
+			"OpenToSpread": e.Target == "applebooks" && !e.Image.View.PortraitOnly,
+		})},
+		{"OEBPS/content.opf", epubtemplates.Content(&epubtemplates.ContentOptions{
+			Title:        title,
+			HasTitlePage: hasTitlePage,
+			HasInfoPage:  hasInfoPage,
+			AppleBooks:   e.Target == "applebooks",
+			Kobo:         e.Target == "kobo",
+			HasRegions:   hasRegions,
+			UID:          e.UID,
+			Author:       e.Author,
+			Series:       e.Series,
+			Volume:       e.Volume,
+			Year:         e.Year,
+			Language:     e.Language,
+			Publisher:    e.Publisher,
+			OwnerTag:     e.OwnerTag,
+			UpdatedAt:    e.UpdatedAt,
+			ImageOptions: e.Image,
+			Cover:        part.Cover,
+			Images:       part.Images,
+			Thumbnails:   thumbnails,
+			Current:      current,
+			Total:        total,
+		})},
+		{"OEBPS/toc.xhtml", epubtemplates.Toc(title, hasTitlePage, e.StripFirstDirectoryFromToc, e.MinChapterPages, part.Images, thumbnails)},
+		{"OEBPS/toc.ncx", epubtemplates.TocNcx(e.UID, title, hasTitlePage, e.StripFirstDirectoryFromToc, e.MinChapterPages, part.Images)},
+		{"OEBPS/Text/style.css", e.render(epubtemplates.Style, map[string]any{
+			"View":         e.Image.View,
+			"InfoFontSize": e.Image.View.Width / 20,
+		})},
+	}
+
+	if hasRegions {
+		content = append(content, zipContent{"OEBPS/regions.xhtml", epubtemplates.Regions(title, part.Images, e.panels)})
+	}
+
+	if err := wz.WriteMagic(); err != nil {
+		return err
+	}
+	for _, c := range content {
+		if err := wz.WriteContent(c.Name, []byte(c.Content)); err != nil {
 			return err
 		}
-		for _, c := range content {
-			if err := wz.WriteContent(c.Name, []byte(c.Content)); err != nil {
-				return err
-			}
+	}
+
+	if err := e.writeCoverImage(wz, part.Cover, current, total); err != nil {
+		return err
+	}
+
+	if hasTitlePage {
+		if err := e.writeTitleImage(wz, part.Cover, title); err != nil {
+			return err
 		}
+	}
+
+	if err := e.writeThumbnails(wz, imgStorage, part.Images, thumbnails); err != nil {
+		return err
+	}
 
-		if err = e.writeCoverImage(wz, part.Cover, i+1, totalParts); err != nil {
+	if hasInfoPage {
+		if err := e.writeInfoPage(wz, e.Title, part.Images, current, total); err != nil {
 			return err
 		}
+	}
 
-		if hasTitlePage {
-			if err = e.writeTitleImage(wz, part.Cover, title); err != nil {
+	lastImage := part.Images[len(part.Images)-1]
+	for _, img := range part.Images {
+		if img.BlankBefore {
+			if err := e.writePadding(wz); err != nil {
 				return err
 			}
 		}
 
-		lastImage := part.Images[len(part.Images)-1]
-		for _, img := range part.Images {
-			if err := e.writeImage(wz, img, imgStorage.Get(img.EPUBImgPath())); err != nil {
-				return err
-			}
+		if err := e.writeImage(wz, img, imgStorage.Get(img.EPUBImgPath())); err != nil {
+			return err
+		}
 
-			// Double Page or Last Image that is not a double page
-			if !e.Image.View.PortraitOnly && (img.DoublePage || (img.Part == 0 && img == lastImage)) {
-				if err := e.writeBlank(wz, img); err != nil {
-					return err
-				}
+		// Double Page or Last Image that is not a double page
+		if !e.Image.View.PortraitOnly && (img.DoublePage || (img.Part == 0 && img == lastImage)) {
+			if err := e.writeBlank(wz, img); err != nil {
+				return err
 			}
 		}
-		bar.Add(1)
 	}
-	bar.Close()
-	fmt.Fprintln(os.Stderr)
 
 	return nil
 }
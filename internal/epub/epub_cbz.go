@@ -0,0 +1,39 @@
+package epub
+
+import (
+	"fmt"
+
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+	epubzip "github.com/celogeek/go-comic-converter/v2/internal/epub/zip"
+)
+
+// write the parts as cbz, only the converted images in reading order.
+//
+// the images are named by their position, zero padded to 4 digits or more, so every reader sorts them the same way.
+// the cover is the first image of each part.
+type cbzWriter struct {
+	*ePub
+}
+
+func (e *cbzWriter) writePart(wz *epubzip.EPUBZip, title string, part *epubPart, current, total int, imgStorage *epubzip.EPUBZipStorageImageReader) error {
+	images := part.Images
+	if e.Image.HasCover {
+		images = append([]*epubimage.Image{part.Cover}, images...)
+	}
+
+	fmtLen := max(4, len(fmt.Sprint(len(images))))
+	for i, img := range images {
+		zipImg := imgStorage.Get(img.EPUBImgPath())
+		if zipImg == nil {
+			return fmt.Errorf("image %s not found", img.EPUBImgPath())
+		}
+		ext := img.Format
+		if ext == "jpeg" {
+			ext = "jpg"
+		}
+		if err := wz.CopyAs(zipImg, fmt.Sprintf("%0*d.%s", fmtLen, i+1, ext)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package epub
+
+import (
+	"archive/zip"
+	"image"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+	epubzip "github.com/celogeek/go-comic-converter/v2/internal/epub/zip"
+)
+
+// pages of two chapters of three pages each
+func chapterPages() []*epubimage.Image {
+	var images []*epubimage.Image
+	for id, path := range []string{"ch1", "ch1", "ch1", "ch2", "ch2", "ch2"} {
+		images = append(images, &epubimage.Image{Id: id, Path: path})
+	}
+	return images
+}
+
+func TestPartImages(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		output     string
+		titlePage  int
+		thumbnails bool
+		want       int
+	}{
+		{"cover only", "book.epub", 0, false, 7},
+		{"title", "book.epub", 2, false, 8},
+		{"thumbnails", "book.epub", 1, true, 10},
+		{"cbz", "book.cbz", 1, true, 7},
+	} {
+		e := New(&epuboptions.Options{
+			Output:        c.output,
+			TitlePage:     c.titlePage,
+			TocThumbnails: c.thumbnails,
+			Image:         &epuboptions.Image{HasCover: true},
+		})
+		if got := e.partImages(chapterPages()); got != c.want {
+			t.Errorf("%s: %d images, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+// content of a file of the zip
+func zipContent(t *testing.T, path, name string) string {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name == name {
+			fr, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fr.Close()
+			b, err := io.ReadAll(fr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(b)
+		}
+	}
+	t.Fatalf("no %s in %s", name, path)
+	return ""
+}
+
+func TestPageViewport(t *testing.T) {
+	dir := t.TempDir()
+	e := New(&epuboptions.Options{Image: &epuboptions.Image{View: &epuboptions.View{Width: 1072, Height: 1448}}})
+	img := &epubimage.Image{Id: 1, Width: 1000, Height: 1448, Format: "jpeg"}
+
+	storage, err := epubzip.NewEPUBZipStorageImageWriter(filepath.Join(dir, "images.zip"), "jpeg", false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Add(img.EPUBImgPath(), image.NewGray(image.Rect(0, 0, img.Width, img.Height)), 80); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+	images, err := epubzip.NewEPUBZipStorageImageReader(filepath.Join(dir, "images.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer images.Close()
+
+	output := filepath.Join(dir, "book.epub")
+	wz, err := epubzip.New(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.writeImage(wz, img, images.Get(img.EPUBImgPath())); err != nil {
+		t.Fatal(err)
+	}
+	if err := wz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fixed layout page has the size of the device
+	page := zipContent(t, output, img.EPUBPagePath())
+	if want := `<meta name="viewport" content="width=1072,height=1448"/>`; !strings.Contains(page, want) {
+		t.Errorf("no %s in\n%s", want, page)
+	}
+}
+
+func TestSplitPartsNoZip64(t *testing.T) {
+	pages := func(n int) []*epubimage.Image {
+		images := make([]*epubimage.Image, n)
+		for i := range images {
+			images[i] = &epubimage.Image{Id: i + 1, Format: "jpeg"}
+		}
+		return images
+	}
+	cover := &epubimage.Image{Id: 0, Format: "jpeg"}
+	maxImages := (epubzip.MaxEntriesWithoutZip64 - 32) / 2
+
+	for _, c := range []struct {
+		name    string
+		noZip64 bool
+		pages   int
+		size    uint64
+		want    []int // pages by part
+	}{
+		{"entries under the limit", true, maxImages, 1024, []int{maxImages}},
+		{"entries over the limit", true, maxImages + 1, 1024, []int{maxImages, 1}},
+		{"entries with zip64", false, maxImages + 1, 1024, []int{maxImages + 1}},
+		// 41 pages of 100Mb go over 4Gb
+		{"size under the limit", true, 40, 100 << 20, []int{40}},
+		{"size over the limit", true, 41, 100 << 20, []int{40, 1}},
+		{"size with zip64", false, 41, 100 << 20, []int{41}},
+	} {
+		e := New(&epuboptions.Options{NoZip64: c.noZip64, Image: &epuboptions.Image{HasCover: true}})
+		parts := e.splitParts(cover, pages(c.pages), func(filename string) uint64 {
+			if filename == cover.EPUBImgPath() {
+				return 1024
+			}
+			return c.size
+		})
+		var got []int
+		for _, p := range parts {
+			got = append(got, len(p.Images))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: parts of %v pages, want %v", c.name, got, c.want)
+		}
+	}
+}
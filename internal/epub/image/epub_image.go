@@ -18,6 +18,7 @@ type Image struct {
 	IsCover             bool
 	IsBlank             bool
 	DoublePage          bool
+	BlankBefore         bool
 	Path                string
 	Name                string
 	Position            string
@@ -25,6 +26,18 @@ type Image struct {
 	OriginalAspectRatio float64
 }
 
+// index of n elements evenly spaced among total, starting with the first one. All are returned if n is 0 or more than total.
+func Sample(total, n int) []int {
+	if n <= 0 || n > total {
+		n = total
+	}
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i * total / n
+	}
+	return indexes
+}
+
 // key name of the blank plage after the image
 func (i *Image) SpaceKey() string {
 	return fmt.Sprintf("space_%d", i.Id)
@@ -70,6 +83,21 @@ func (i *Image) EPUBImgPath() string {
 	return fmt.Sprintf("OEBPS/%s", i.ImgPath())
 }
 
+// key for the thumbnail of the chapter
+func (i *Image) ThumbKey() string {
+	return fmt.Sprintf("thumb_%d_p%d", i.Id, i.Part)
+}
+
+// thumbnail path
+func (i *Image) ThumbPath() string {
+	return fmt.Sprintf("Images/%s.%s", i.ThumbKey(), i.Format)
+}
+
+// thumbnail path into the EPUB
+func (i *Image) EPUBThumbPath() string {
+	return fmt.Sprintf("OEBPS/%s", i.ThumbPath())
+}
+
 // style to apply to the image.
 //
 // center by default.
@@ -0,0 +1,74 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/gift"
+)
+
+// Stretch the levels of the image, the darkest gray become black and the lightest white.
+//
+// clip is the percent of the pixels ignored at each end of the histogram, so a few dark or light dots
+// (dust, a logo) don't prevent the stretch. The same transfer is applied to the 3 channels to keep the colors.
+func AutoContrast(clip float64) gift.Filter {
+	return &autoContrast{clip}
+}
+
+type autoContrast struct {
+	clip float64
+}
+
+func (p *autoContrast) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	return srcBounds
+}
+
+func (p *autoContrast) Draw(dst draw.Image, src image.Image, options *gift.Options) {
+	lo, hi := p.levels(src)
+	if hi <= lo {
+		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+		return
+	}
+
+	min, scale := float32(lo)/255, 255/float32(hi-lo)
+	gift.ColorFunc(func(r0, g0, b0, a0 float32) (r float32, g float32, b float32, a float32) {
+		return stretch(r0, min, scale), stretch(g0, min, scale), stretch(b0, min, scale), a0
+	}).Draw(dst, src, options)
+}
+
+// darkest and lightest gray of the image, after ignoring the clip percent at each end.
+func (p *autoContrast) levels(src image.Image) (lo, hi int) {
+	var histogram [256]int
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			histogram[color.GrayModel.Convert(src.At(x, y)).(color.Gray).Y]++
+		}
+	}
+
+	clipped := int(float64(b.Dx()*b.Dy()) * p.clip / 100)
+	lo, hi = 0, 255
+	for n := histogram[lo]; lo < 255 && n <= clipped; n += histogram[lo] {
+		lo++
+	}
+	for n := histogram[hi]; hi > 0 && n <= clipped; n += histogram[hi] {
+		hi--
+	}
+	return
+}
+
+func stretch(v, min, scale float32) float32 {
+	return clamp((v - min) * scale)
+}
+
+// keep a level between black and white
+func clamp(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
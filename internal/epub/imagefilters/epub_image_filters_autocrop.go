@@ -8,20 +8,55 @@ import (
 )
 
 // Lookup for margin and crop
-func AutoCrop(img image.Image, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom int) gift.Filter {
+func AutoCrop(img image.Image, threshold int, minRatio float64, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom int) gift.Filter {
 	return gift.Crop(
-		findMarging(img, cutRatioOptions{cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom}),
+		AutoCropBox(img, threshold, minRatio, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom),
 	)
 }
 
+// Lookup for margin and return the area to keep
+//
+// a pixel is blank when its gray is at least the threshold. The area keeps at least minRatio of the width and
+// the height of the image, unless the image is blank (empty area).
+func AutoCropBox(img image.Image, threshold int, minRatio float64, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom int) image.Rectangle {
+	box := findMarging(img, cutRatioOptions{threshold, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom})
+	if box.Empty() || minRatio <= 0 {
+		return box
+	}
+	b := img.Bounds()
+	box.Min.X, box.Max.X = keepMinSize(box.Min.X, box.Max.X, b.Min.X, b.Max.X, int(float64(b.Dx())*minRatio))
+	box.Min.Y, box.Max.Y = keepMinSize(box.Min.Y, box.Max.Y, b.Min.Y, b.Max.Y, int(float64(b.Dy())*minRatio))
+	return box
+}
+
+// grow [min, max) around its center to the size, inside [lo, hi)
+func keepMinSize(min, max, lo, hi, size int) (int, int) {
+	grow := size - (max - min)
+	if grow <= 0 {
+		return min, max
+	}
+	min, max = min-grow/2, max+grow-grow/2
+	if min < lo {
+		min, max = lo, max+lo-min
+	}
+	if max > hi {
+		min, max = min-(max-hi), hi
+	}
+	if min < lo {
+		min = lo
+	}
+	return min, max
+}
+
 // check if the color is blank enough
-func colorIsBlank(c color.Color) bool {
+func colorIsBlank(c color.Color, threshold int) bool {
 	g := color.GrayModel.Convert(c).(color.Gray)
-	return g.Y >= 0xe0
+	return int(g.Y) >= threshold
 }
 
 // lookup for margin (blank) around the image
 type cutRatioOptions struct {
+	Threshold               int
 	Left, Up, Right, Bottom int
 }
 
@@ -32,7 +67,7 @@ LEFT:
 	for x := imgArea.Min.X; x < imgArea.Max.X; x++ {
 		allowNonBlank := imgArea.Dy() * cutRatio.Left / 100
 		for y := imgArea.Min.Y; y < imgArea.Max.Y; y++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), cutRatio.Threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break LEFT
@@ -46,7 +81,7 @@ UP:
 	for y := imgArea.Min.Y; y < imgArea.Max.Y; y++ {
 		allowNonBlank := imgArea.Dx() * cutRatio.Up / 100
 		for x := imgArea.Min.X; x < imgArea.Max.X; x++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), cutRatio.Threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break UP
@@ -60,7 +95,7 @@ RIGHT:
 	for x := imgArea.Max.X - 1; x >= imgArea.Min.X; x-- {
 		allowNonBlank := imgArea.Dy() * cutRatio.Right / 100
 		for y := imgArea.Min.Y; y < imgArea.Max.Y; y++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), cutRatio.Threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break RIGHT
@@ -74,7 +109,7 @@ BOTTOM:
 	for y := imgArea.Max.Y - 1; y >= imgArea.Min.Y; y-- {
 		allowNonBlank := imgArea.Dx() * cutRatio.Bottom / 100
 		for x := imgArea.Min.X; x < imgArea.Max.X; x++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), cutRatio.Threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break BOTTOM
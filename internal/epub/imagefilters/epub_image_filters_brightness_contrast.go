@@ -0,0 +1,32 @@
+package epubimagefilters
+
+import (
+	"github.com/disintegration/gift"
+)
+
+// Readjust the brightness and the contrast, both between -100 and 100.
+//
+// The contrast is applied around the middle gray, then the brightness shifts the result. Both are computed
+// in one pass and clamped once, so a level pushed to white by the contrast is not darkened back by the brightness.
+func BrightnessContrast(brightness, contrast int) gift.Filter {
+	shift := float32(brightness) / 100
+
+	// same curve as gift.Contrast: reduced around the middle gray below 0, strengthened above, black or white at 100
+	level := func(v float32) float32 {
+		switch {
+		case contrast <= 0:
+			v = 0.5 + (v-0.5)*(1+float32(contrast)/100)
+		case contrast < 100:
+			v = 0.5 + (v-0.5)/(1-float32(contrast)/100)
+		case v < 0.5:
+			v = 0
+		default:
+			v = 1
+		}
+		return clamp(v + shift)
+	}
+
+	return gift.ColorFunc(func(r0, g0, b0, a0 float32) (r float32, g float32, b float32, a float32) {
+		return level(r0), level(g0), level(b0), a0
+	})
+}
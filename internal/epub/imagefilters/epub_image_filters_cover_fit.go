@@ -0,0 +1,46 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/gift"
+)
+
+// Adjust the cover to an aspect ratio (height/width), the device one by default.
+//
+// fill crops the center of the cover, pad adds the background color around it.
+func CoverFit(mode string, aspectRatio float64, background color.Color) gift.Filter {
+	return &coverFit{mode, aspectRatio, background}
+}
+
+type coverFit struct {
+	mode        string
+	aspectRatio float64
+	background  color.Color
+}
+
+func (p *coverFit) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	w, h := srcBounds.Dx(), srcBounds.Dy()
+	taller := float64(h)/float64(w) > p.aspectRatio
+	if (p.mode == "fill") == taller {
+		h = int(float64(w) * p.aspectRatio)
+	} else {
+		w = int(float64(h) / p.aspectRatio)
+	}
+	return image.Rect(0, 0, w, h)
+}
+
+func (p *coverFit) Draw(dst draw.Image, src image.Image, options *gift.Options) {
+	if p.mode == "fill" {
+		gift.CropToSize(dst.Bounds().Dx(), dst.Bounds().Dy(), gift.CenterAnchor).Draw(dst, src, options)
+		return
+	}
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(p.background), image.Point{}, draw.Src)
+	offset := image.Pt(
+		(dst.Bounds().Dx()-src.Bounds().Dx())/2,
+		(dst.Bounds().Dy()-src.Bounds().Dy())/2,
+	)
+	draw.Draw(dst, src.Bounds().Sub(src.Bounds().Min).Add(dst.Bounds().Min).Add(offset), src, src.Bounds().Min, draw.Src)
+}
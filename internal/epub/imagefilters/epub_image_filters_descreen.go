@@ -0,0 +1,13 @@
+package epubimagefilters
+
+import (
+	"github.com/disintegration/gift"
+)
+
+// Remove the halftone dots of a print before reducing the image by the given ratio.
+//
+// The dots finer than a pixel of the output are blurred out (low-pass filter), so they don't create a moiré once resized.
+// The image is expected to be reduced, ratio > 1.
+func Descreen(ratio float64) gift.Filter {
+	return gift.GaussianBlur(float32(ratio / 2))
+}
@@ -0,0 +1,69 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/gift"
+)
+
+const (
+	deskewMaxAngle = 5.0
+	deskewStep     = 0.1
+	deskewWidth    = 600
+)
+
+// Estimate the rotation (in degrees, counter-clockwise) to straighten a skewed scan.
+//
+// The dark pixels are projected on the rows for each small angle, the angle that best align them
+// (the sum of the squared row counts is the highest) is kept. 0 is returned if the page is straight or empty.
+func DeskewAngle(src image.Image) float32 {
+	// work on a small version, the gutters and the lines remain visible
+	g := gift.New(gift.ResizeToFit(deskewWidth, deskewWidth*2, gift.BoxResampling))
+	img := image.NewGray(g.Bounds(src.Bounds()))
+	g.Draw(img, src)
+
+	b := img.Bounds()
+	points := make([]image.Point, 0)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.GrayAt(x, y).Y < 128 {
+				points = append(points, image.Pt(x-b.Min.X, y-b.Min.Y))
+			}
+		}
+	}
+	if len(points) == 0 {
+		return 0
+	}
+
+	score := func(angle float64) float64 {
+		tan := math.Tan(angle * math.Pi / 180)
+		offset := int(math.Ceil(float64(b.Dx()) * math.Abs(tan)))
+		rows := make([]float64, b.Dy()+2*offset+1)
+		for _, p := range points {
+			rows[int(math.Round(float64(p.Y)-float64(p.X)*tan))+offset]++
+		}
+		s := 0.0
+		for _, r := range rows {
+			s += r * r
+		}
+		return s
+	}
+
+	bestAngle, bestScore := 0.0, score(0)
+	for i := 1; float64(i)*deskewStep <= deskewMaxAngle; i++ {
+		for _, angle := range []float64{float64(i) * deskewStep, -float64(i) * deskewStep} {
+			if s := score(angle); s > bestScore {
+				bestAngle, bestScore = angle, s
+			}
+		}
+	}
+
+	return float32(bestAngle)
+}
+
+// Rotate the image to straighten it, the corners are filled with the background.
+func Deskew(angle float32, background color.Color) gift.Filter {
+	return gift.Rotate(angle, background, gift.CubicInterpolation)
+}
@@ -0,0 +1,35 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/gift"
+)
+
+// Reduce the image to the given number of levels of gray, with the Floyd–Steinberg error diffusion.
+//
+// The error of each pixel is spread to its neighbours, so the gradients are rendered with a pattern of the nearest
+// levels instead of bands. The e-ink screens display 16 levels of gray, some older ones only 4.
+func Dither(levels int) gift.Filter {
+	palette := make(color.Palette, levels)
+	for i := range palette {
+		palette[i] = color.Gray{uint8(i * 0xff / (levels - 1))}
+	}
+	return &dither{palette}
+}
+
+type dither struct {
+	palette color.Palette
+}
+
+func (p *dither) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	return srcBounds
+}
+
+func (p *dither) Draw(dst draw.Image, src image.Image, options *gift.Options) {
+	img := image.NewPaletted(src.Bounds(), p.palette)
+	draw.FloydSteinberg.Draw(img, img.Bounds(), src, src.Bounds().Min)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+}
@@ -0,0 +1,42 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/gift"
+)
+
+func TestDitherPalette(t *testing.T) {
+	// a color gradient, made gray before the dithering like the pages
+	src := image.NewRGBA(image.Rect(0, 0, 256, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			src.Set(x, y, color.RGBA{uint8(x), uint8(255 - x), uint8(y * 4), 0xff})
+		}
+	}
+
+	for _, levels := range []int{4, 16} {
+		palette := map[uint8]bool{}
+		for i := 0; i < levels; i++ {
+			palette[uint8(i*0xff/(levels-1))] = true
+		}
+
+		g := gift.New(gift.Grayscale(), Dither(levels))
+		dst := image.NewGray(g.Bounds(src.Bounds()))
+		g.Draw(dst, src)
+
+		used := map[uint8]bool{}
+		for _, p := range dst.Pix {
+			if !palette[p] {
+				t.Fatalf("%d levels: gray %d not in the palette", levels, p)
+			}
+			used[p] = true
+		}
+		// the gradient is rendered with several levels, not a flat gray
+		if len(used) < levels/2 {
+			t.Errorf("%d levels: only %d used", levels, len(used))
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/gift"
+)
+
+// Add a border of the given size and color around the image.
+//
+// Used after the crop, to keep a margin between the panels and the bezel of the device.
+func Pad(size int, background color.Color) gift.Filter {
+	return &pad{size, background}
+}
+
+type pad struct {
+	size       int
+	background color.Color
+}
+
+func (p *pad) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	return image.Rect(0, 0, srcBounds.Dx()+2*p.size, srcBounds.Dy()+2*p.size)
+}
+
+func (p *pad) Draw(dst draw.Image, src image.Image, options *gift.Options) {
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(p.background), image.Point{}, draw.Src)
+	offset := dst.Bounds().Min.Add(image.Pt(p.size, p.size))
+	draw.Draw(dst, src.Bounds().Sub(src.Bounds().Min).Add(offset), src, src.Bounds().Min, draw.Src)
+}
@@ -0,0 +1,98 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/golang/freetype"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// smallest font of a text page, a longer text is cut at the bottom of the page
+const minTextFontSize = 8
+
+// Render a text on a page of the given size, like a colophon or the credits.
+//
+// The lines starting with "#" are headings in bold. The words are wrapped to the width of the page,
+// and the font is reduced from fontSize until the text fit in the page, down to minTextFontSize.
+func TextPage(text string, width, height, fontSize int, foreground, background color.Color) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	regular, _ := truetype.Parse(goregular.TTF)
+	bold, _ := truetype.Parse(gobold.TTF)
+
+	type line struct {
+		text string
+		font *truetype.Font
+		size float64
+	}
+
+	margin := width / 20
+	fontSize = max(fontSize, minTextFontSize)
+	var lines []line
+	for {
+		lines = lines[:0]
+		textHeight := 0
+		for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+			f, size := regular, float64(fontSize)
+			if strings.HasPrefix(paragraph, "#") {
+				f, size = bold, size*1.5
+				paragraph = strings.TrimSpace(strings.TrimLeft(paragraph, "#"))
+			}
+			face := truetype.NewFace(f, &truetype.Options{Size: size, DPI: 72})
+			lineHeight := int(size * 1.4)
+
+			// empty line between paragraphs
+			if strings.TrimSpace(paragraph) == "" {
+				lines = append(lines, line{"", f, size})
+				textHeight += lineHeight
+				continue
+			}
+
+			current := ""
+			for _, word := range strings.Fields(paragraph) {
+				next := word
+				if current != "" {
+					next = current + " " + word
+				}
+				if current != "" && font.MeasureString(face, next).Ceil() > width-2*margin {
+					lines = append(lines, line{current, f, size})
+					textHeight += lineHeight
+					next = word
+				}
+				current = next
+			}
+			lines = append(lines, line{current, f, size})
+			textHeight += lineHeight
+		}
+		if textHeight <= height-2*margin || fontSize <= minTextFontSize {
+			break
+		}
+		fontSize--
+	}
+
+	c := freetype.NewContext()
+	c.SetDPI(72)
+	c.SetClip(dst.Bounds())
+	c.SetDst(dst)
+	c.SetSrc(image.NewUniform(foreground))
+
+	y := margin
+	for _, l := range lines {
+		y += int(l.size * 1.4)
+		if l.text == "" {
+			continue
+		}
+		c.SetFont(l.font)
+		c.SetFontSize(l.size)
+		c.DrawString(l.text, freetype.Pt(margin, y))
+	}
+
+	return dst
+}
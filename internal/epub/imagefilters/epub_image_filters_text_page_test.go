@@ -0,0 +1,42 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// number of pixels not of the background
+func inked(img image.Image) int {
+	n := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestTextPageSmallFont(t *testing.T) {
+	// h/50 of a small page is below the smallest font
+	for _, fontSize := range []int{0, 5, 8, 20} {
+		img := TextPage("# Credits\nScan and edit by someone", 300, 300, fontSize, color.Black, color.White)
+		if inked(img) == 0 {
+			t.Errorf("font %d: blank page", fontSize)
+		}
+	}
+}
+
+func TestTextPageTooLong(t *testing.T) {
+	// the text is cut at the smallest font, not skipped
+	text := ""
+	for i := 0; i < 200; i++ {
+		text += "a line of the colophon\n"
+	}
+	if inked(TextPage(text, 200, 200, 20, color.Black, color.White)) == 0 {
+		t.Error("blank page for a text longer than the page")
+	}
+}
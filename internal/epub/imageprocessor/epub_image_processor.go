@@ -4,12 +4,17 @@ Extract and transform image into a compressed jpeg.
 package epubimageprocessor
 
 import (
+	"archive/zip"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
 
 	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
 	epubimagefilters "github.com/celogeek/go-comic-converter/v2/internal/epub/imagefilters"
@@ -21,20 +26,50 @@ import (
 
 type EPUBImageProcessor struct {
 	*epuboptions.Options
+	firstCrop      *firstPageCrop
+	skipped        int
+	unreadable     int64
+	directionFound bool
+
+	// the selected cover is also at its place, before this page ("" at the end).
+	//
+	// the name is the one given to selectNames, its id is resolved by indexNames (-1 at the end).
+	coverCopy         bool
+	coverCopyBefore   string
+	coverCopyBeforeId int
+}
+
+// margins found on the first page
+type firstPageCrop struct {
+	Size image.Point
+	Box  image.Rectangle
 }
 
 func New(o *epuboptions.Options) *EPUBImageProcessor {
-	return &EPUBImageProcessor{o}
+	return &EPUBImageProcessor{Options: o}
 }
 
 // extract and convert images
 func (e *EPUBImageProcessor) Load() (images []*epubimage.Image, err error) {
 	images = make([]*epubimage.Image, 0)
+
+	var colophon []byte
+	if e.Colophon != "" && !e.Dry {
+		if colophon, err = os.ReadFile(e.Colophon); err != nil {
+			return nil, err
+		}
+	}
+
 	imageCount, imageInput, err := e.load()
 	if err != nil {
 		return nil, err
 	}
 
+	if e.coverCopy {
+		imageInput = e.copyCover(imageInput)
+		imageCount++
+	}
+
 	// dry run, skip convertion
 	if e.Dry {
 		for img := range imageInput {
@@ -49,6 +84,31 @@ func (e *EPUBImageProcessor) Load() (images []*epubimage.Image, err error) {
 		return images, nil
 	}
 
+	if e.Image.AutoDirection && !e.directionFound {
+		imageInput = e.detectDirection(imageInput, imageCount)
+	}
+
+	if e.Image.Crop.Enabled && e.Image.Crop.FromFirst {
+		imageInput = e.cropFromFirst(imageInput, imageCount)
+	}
+
+	if e.Image.HasCover && e.Image.CoverBack != 0 {
+		imageInput = e.coverSpread(imageInput, imageCount)
+	}
+
+	if e.SkipBad {
+		imageInput = e.skipBad(imageInput)
+	}
+
+	if e.Image.LongStrip {
+		imageInput = e.longStrip(imageInput)
+	}
+
+	if colophon != nil {
+		imageInput = e.appendTextPage(imageInput, "colophon", string(colophon))
+		imageCount++
+	}
+
 	imageOutput := make(chan *epubimage.Image)
 
 	// processing
@@ -61,12 +121,69 @@ func (e *EPUBImageProcessor) Load() (images []*epubimage.Image, err error) {
 	})
 	wg := &sync.WaitGroup{}
 
-	imgStorage, err := epubzip.NewEPUBZipStorageImageWriter(e.ImgStorage(), e.Image.Format)
+	imgStorage, err := epubzip.NewEPUBZipStorageImageWriter(e.ImgStorage(), e.Image.Format, e.Image.Lossless, uint64(e.Image.BytesPerPage))
 	if err != nil {
 		bar.Close()
 		return nil, err
 	}
 
+	// the same images encoded with the other qualities
+	type qualityStorage struct {
+		quality int
+		storage *epubzip.EPUBZipStorageImageWriter
+	}
+	storages := []qualityStorage{{e.Image.Quality, imgStorage}}
+	for i := 1; i < len(e.Image.Qualities); i++ {
+		q := e.Image.Qualities[i]
+		s, err := epubzip.NewEPUBZipStorageImageWriter(e.QualityImgStorage(q), e.Image.Format, e.Image.Lossless, uint64(e.Image.BytesPerPage))
+		if err != nil {
+			bar.Close()
+			for _, s := range storages {
+				s.storage.Close()
+			}
+			return nil, err
+		}
+		storages = append(storages, qualityStorage{q, s})
+	}
+
+	// the cleaned pages at their original resolution
+	var archive *epubzip.EPUBZipStorageImageWriter
+	if e.ArchiveCbz != "" {
+		if archive, err = epubzip.NewEPUBZipStorageImageWriter(e.ArchiveCbz, "jpeg", false, 0); err != nil {
+			bar.Close()
+			for _, s := range storages {
+				s.storage.Close()
+			}
+			return nil, err
+		}
+	}
+
+	// the unreadable pages dropped by skipbad are no more in the total
+	var done int64
+	var progressMu sync.Mutex
+	progress := func(pages int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		total := imageCount - int(atomic.LoadInt64(&e.unreadable))
+		if total != bar.GetMax() {
+			bar.ChangeMax(total)
+		}
+		bar.Add(pages)
+		done += int64(pages)
+		if e.OnProgress != nil {
+			e.OnProgress(int(done), total)
+		}
+	}
+
+	// the first error stops the conversion, the pages still sent by the loader are drained
+	failure := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case failure <- err:
+		default:
+		}
+	}
+
 	wr := 50
 	if e.Image.Format == "png" {
 		wr = 100
@@ -77,9 +194,38 @@ func (e *EPUBImageProcessor) Load() (images []*epubimage.Image, err error) {
 			defer wg.Done()
 
 			for input := range imageInput {
+				if len(failure) > 0 {
+					continue
+				}
+				if input.Error != nil {
+					fail(fmt.Errorf("error processing image %s: %w", filepath.Join(input.Path, input.Name), input.Error))
+					continue
+				}
+
 				src := input.Image
+				doublePage := input.isDoublePage(e.Image.DoublePageRatio)
+
+				var dsts []image.Image
+				rawPage := e.isRawPage(input, doublePage)
+				if rawPage || input.Rendered {
+					dsts = []image.Image{src}
+				} else {
+					src = e.deskew(src)
+					dsts = e.transformImage(src, input.Id, doublePage)
+				}
+
+				if archive != nil && !input.Rendered {
+					if err := e.archiveImage(archive, input, src, imageCount); err != nil {
+						fail(fmt.Errorf("error with %s: %w", input.Name, err))
+						continue
+					}
+				}
+				for part, dst := range dsts {
+					// only the halves of the double page are kept, except for the cover
+					if part == 0 && len(dsts) == 3 && e.Image.SpreadMode == "split" && input.Id != 0 {
+						continue
+					}
 
-				for part, dst := range e.transformImage(src, input.Id) {
 					var raw image.Image
 					if input.Id == 0 && part == 0 {
 						raw = dst
@@ -93,41 +239,67 @@ func (e *EPUBImageProcessor) Load() (images []*epubimage.Image, err error) {
 						Height:              dst.Bounds().Dy(),
 						IsCover:             input.Id == 0 && part == 0,
 						IsBlank:             dst.Bounds().Dx() == 1 && dst.Bounds().Dy() == 1,
-						DoublePage:          part == 0 && src.Bounds().Dx() > src.Bounds().Dy(),
+						DoublePage:          part == 0 && doublePage,
 						Path:                input.Path,
 						Name:                input.Name,
 						Format:              e.Image.Format,
 						OriginalAspectRatio: float64(src.Bounds().Dy()) / float64(src.Bounds().Dx()),
 					}
 
-					if err = imgStorage.Add(img.EPUBImgPath(), dst, e.Image.Quality); err != nil {
-						bar.Close()
-						fmt.Fprintf(os.Stderr, "error with %s: %s", input.Name, err)
-						os.Exit(1)
+					var err error
+					for _, s := range storages {
+						if rawPage {
+							err = s.storage.AddRaw(img.EPUBImgPath(), input.Jpeg)
+						} else {
+							err = s.storage.Add(img.EPUBImgPath(), dst, s.quality)
+						}
+						if err != nil {
+							break
+						}
+					}
+					if err != nil {
+						fail(fmt.Errorf("error with %s: %w", input.Name, err))
+						break
 					}
 					imageOutput <- img
 				}
+				pages := 1
+				if input.Pages > 1 {
+					pages = input.Pages
+				}
+				progress(pages)
 			}
 		}()
 	}
 
 	go func() {
 		wg.Wait()
-		imgStorage.Close()
+		for _, s := range storages {
+			s.storage.Close()
+		}
+		if archive != nil {
+			archive.Close()
+		}
 		close(imageOutput)
 	}()
 
 	for img := range imageOutput {
-		if img.Part == 0 {
-			bar.Add(1)
-		}
 		if e.Image.NoBlankImage && img.IsBlank {
+			e.skipped++
 			continue
 		}
 		images = append(images, img)
 	}
+	// the last pages may have been dropped after the others were processed
+	progress(0)
 	bar.Close()
 
+	select {
+	case err := <-failure:
+		return nil, err
+	default:
+	}
+
 	if len(images) == 0 {
 		return nil, errNoImagesFound
 	}
@@ -135,6 +307,122 @@ func (e *EPUBImageProcessor) Load() (images []*epubimage.Image, err error) {
 	return images, nil
 }
 
+// keep the original jpeg of a page that fit the device without split or rotation
+func (e *EPUBImageProcessor) isRawPage(input *tasks, doublePage bool) bool {
+	if !e.Image.RawPages || input.Jpeg == nil || input.Id == 0 {
+		return false
+	}
+	if doublePage && (e.Image.AutoSplitDoublePage || e.Image.AutoRotate) {
+		return false
+	}
+	b := input.Image.Bounds()
+	return b.Dx() <= e.Image.View.Width && b.Dy() <= e.Image.View.Height
+}
+
+// number of pages removed by the ComicInfo, or because they are blank or unreadable
+func (e *EPUBImageProcessor) Skipped() int {
+	return e.skipped + int(e.unreadable)
+}
+
+// lookup for the margins of the first pages, and replay it with the pages read before it.
+//
+// the first page is inspected, or the number of sample pages. The margins of the pages of the same size
+// as the first one are merged, so nothing is cut on any of them.
+// if the first pages are blank, each page is cropped with its own margins.
+func (e *EPUBImageProcessor) cropFromFirst(input chan *tasks, total int) chan *tasks {
+	ids := e.sampleIds(total, 1, 0)
+	sampled, output := e.collect(input, ids)
+
+	// merge in reading order, starting with the first page not blank
+	for _, id := range ids {
+		t, ok := sampled[id]
+		if !ok || t.Error != nil {
+			continue
+		}
+		src := t.Image
+		box := epubimagefilters.AutoCropBox(
+			src,
+			e.Image.Crop.Threshold,
+			e.Image.Crop.MinRatio,
+			e.Image.Crop.Left,
+			e.Image.Crop.Up,
+			e.Image.Crop.Right,
+			e.Image.Crop.Bottom,
+		).Sub(src.Bounds().Min)
+		if box.Empty() {
+			continue
+		}
+		size := src.Bounds().Size()
+		if e.firstCrop == nil {
+			e.firstCrop = &firstPageCrop{Size: size, Box: box}
+		} else if e.firstCrop.Size == size {
+			e.firstCrop.Box = e.firstCrop.Box.Union(box)
+		}
+	}
+	return output
+}
+
+// crop with the margins of the first page if it has the same size
+func (e *EPUBImageProcessor) firstPageCropFilter(src image.Image) gift.Filter {
+	if !e.Image.Crop.Enabled || e.firstCrop == nil || src.Bounds().Size() != e.firstCrop.Size {
+		return nil
+	}
+	return gift.Crop(e.firstCrop.Box.Add(src.Bounds().Min))
+}
+
+// add a page with the text after the last page, rendered at the size of the device
+//
+// the page takes the id following the last one received.
+func (e *EPUBImageProcessor) appendTextPage(input chan *tasks, name string, text string) chan *tasks {
+	output := make(chan *tasks, e.Workers)
+	go func() {
+		defer close(output)
+		id := 0
+		for t := range input {
+			if t.Id >= id {
+				id = t.Id + 1
+			}
+			output <- t
+		}
+
+		w, h := e.Image.View.Width, e.Image.View.Height
+		src := epubimagefilters.TextPage(text, w, h, h/50, e.foregroundColor(), e.backgroundColor())
+		dst := e.createImage(src, src.Bounds())
+		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+		output <- &tasks{
+			Id:       id,
+			Image:    dst,
+			Name:     name,
+			Rendered: true,
+		}
+	}()
+	return output
+}
+
+// background color of the view, in hexa format RGB
+func (e *EPUBImageProcessor) backgroundColor() color.Color {
+	return hexColor(e.Image.View.Color.Background, color.White)
+}
+
+// foreground color of the view, in hexa format RGB
+func (e *EPUBImageProcessor) foregroundColor() color.Color {
+	return hexColor(e.Image.View.Color.Foreground, color.Black)
+}
+
+// parse a color in hexa format RGB
+func hexColor(s string, fallback color.Color) color.Color {
+	v, err := strconv.ParseUint(s, 16, 12)
+	if err != nil {
+		return fallback
+	}
+	return color.RGBA{
+		R: uint8(v>>8&0xF) * 0x11,
+		G: uint8(v>>4&0xF) * 0x11,
+		B: uint8(v&0xF) * 0x11,
+		A: 0xFF,
+	}
+}
+
 func (e *EPUBImageProcessor) createImage(src image.Image, r image.Rectangle) draw.Image {
 	if e.Options.Image.GrayScale {
 		return image.NewGray(r)
@@ -166,16 +454,367 @@ func (e *EPUBImageProcessor) createImage(src image.Image, r image.Rectangle) dra
 	}
 }
 
-// transform image into 1 or 3 images
-// only doublepage with autosplit has 3 versions
-func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int) []image.Image {
-	var filters, splitFilters []gift.Filter
-	var images []image.Image
+// crop the center of the page to the aspect ratio of the device, before the resize of the fit mode cover.
+func (e *EPUBImageProcessor) coverFitFilter() gift.Filter {
+	return epubimagefilters.CoverFit("fill", float64(e.Image.View.Height)/float64(e.Image.View.Width), nil)
+}
 
-	// Lookup for margin if crop is enable or if we want to remove blank image
-	if e.Image.Crop.Enabled || e.Image.NoBlankImage {
-		f := epubimagefilters.AutoCrop(
+// resize the page into width x height with the fit mode.
+//
+// fit and cover keep the aspect ratio, contain pads the page to the aspect ratio of the device, stretch distorts it
+// to exactly the size of the device.
+func (e *EPUBImageProcessor) resizeFilters(width, height int, bounds image.Rectangle) []gift.Filter {
+	switch e.Image.FitMode {
+	case "stretch":
+		return []gift.Filter{gift.Resize(width, height, e.resampling(bounds))}
+	case "contain":
+		return []gift.Filter{
+			gift.ResizeToFit(width, height, e.resampling(bounds)),
+			epubimagefilters.CoverFit("pad", float64(height)/float64(width), e.padColor()),
+		}
+	default:
+		return []gift.Filter{gift.ResizeToFit(width, height, e.resampling(bounds))}
+	}
+}
+
+// color of the padding of the fit mode contain, the background color by default
+func (e *EPUBImageProcessor) padColor() color.Color {
+	switch e.Image.PadColor {
+	case "white":
+		return color.White
+	case "black":
+		return color.Black
+	default:
+		return e.backgroundColor()
+	}
+}
+
+// unsharp mask with a radius of 1 pixel, the size of the edges of a text once reduced.
+func (e *EPUBImageProcessor) sharpenFilter() gift.Filter {
+	return gift.UnsharpMask(1, float32(e.Image.SharpenAmount), 0)
+}
+
+// resampling used to fit the image into the view.
+//
+// the resize filter, lanczos by default, area average (box) for big reduction to avoid aliasing of small text.
+func (e *EPUBImageProcessor) resampling(bounds image.Rectangle) gift.Resampling {
+	if e.Image.BoxRatio > 0 {
+		ratio := math.Max(
+			float64(bounds.Dx())/float64(e.Image.View.Width),
+			float64(bounds.Dy())/float64(e.Image.View.Height),
+		)
+		if ratio >= e.Image.BoxRatio {
+			return gift.BoxResampling
+		}
+	}
+	switch e.Image.ResizeFilter {
+	case "nearest":
+		return gift.NearestNeighborResampling
+	case "box":
+		return gift.BoxResampling
+	case "bilinear":
+		return gift.LinearResampling
+	case "catmullrom":
+		return gift.CubicResampling
+	default:
+		return gift.LanczosResampling
+	}
+}
+
+// hold the cover until the back cover is read, and replace it by the wraparound cover.
+//
+// the back cover stays at its place in the pages.
+func (e *EPUBImageProcessor) coverSpread(input chan *tasks, imageCount int) chan *tasks {
+	backId := e.Image.CoverBack - 1
+	if e.Image.CoverBack < 0 {
+		backId = imageCount + e.Image.CoverBack
+	}
+
+	output := make(chan *tasks, e.Workers)
+	go func() {
+		defer close(output)
+		var cover *tasks
+		var back image.Image
+		for t := range input {
+			// unreadable page, reported by the processing
+			if t.Error != nil {
+				output <- t
+				continue
+			}
+			switch t.Id {
+			case 0:
+				cover = t
+			case backId:
+				back = t.Image
+				output <- t
+			default:
+				output <- t
+			}
+
+			if cover != nil && back != nil {
+				output <- e.stitchCover(cover, back)
+				cover, back = nil, nil
+				backId = -1
+			}
+		}
+
+		// back cover not found
+		if cover != nil {
+			fmt.Fprintf(os.Stderr, "back cover (page %d) not found, keeping the cover as is\n", e.Image.CoverBack)
+			output <- cover
+		}
+	}()
+	return output
+}
+
+// place the cover and the back cover side by side, the back cover is resized to the height of the cover.
+//
+// the front is on the right, or on the left in manga mode.
+func (e *EPUBImageProcessor) stitchCover(cover *tasks, back image.Image) *tasks {
+	front := cover.Image
+	if front == nil {
+		return cover
+	}
+
+	h := front.Bounds().Dy()
+	g := gift.New(gift.Resize(0, h, gift.LanczosResampling))
+	resizedBack := image.NewRGBA(g.Bounds(back.Bounds()))
+	g.Draw(resizedBack, back)
+
+	left, right := image.Image(resizedBack), front
+	if e.Image.Manga {
+		left, right = right, left
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, left.Bounds().Dx()+right.Bounds().Dx(), h))
+	draw.Draw(dst, left.Bounds().Sub(left.Bounds().Min), left, left.Bounds().Min, draw.Src)
+	draw.Draw(dst, right.Bounds().Sub(right.Bounds().Min).Add(image.Pt(left.Bounds().Dx(), 0)), right, right.Bounds().Min, draw.Src)
+
+	// the wraparound cover is kept whole
+	doublePage := false
+	return &tasks{
+		Id:         cover.Id,
+		Image:      dst,
+		Path:       cover.Path,
+		Name:       cover.Name,
+		DoublePage: &doublePage,
+	}
+}
+
+// concatenate the pages of each chapter into long strips, in the reading order.
+//
+// the pages are resized to the width of the device (or of the first page of the strip without resize),
+// a new strip is started on a new chapter or when the strip would be taller than the long strip height.
+// the cover stays alone, the strip take the id of its first page.
+func (e *EPUBImageProcessor) longStrip(input chan *tasks) chan *tasks {
+	output := make(chan *tasks, e.Workers)
+	go func() {
+		defer close(output)
+
+		var strip []*tasks
+		stripHeight, stripWidth := 0, 0
+		flush := func() {
+			if len(strip) > 0 {
+				output <- e.stitchStrip(strip, stripWidth, stripHeight)
+			}
+			strip, stripHeight, stripWidth = nil, 0, 0
+		}
+
+		// the pages are read in parallel, they are put back in order
+		pending := make(map[int]*tasks)
+		next := 0
+		for t := range input {
+			pending[t.Id] = t
+			for {
+				t, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				if t.Image == nil || (e.Image.HasCover && t.Id == 0) {
+					output <- t
+					continue
+				}
+
+				b := t.Image.Bounds()
+				if len(strip) > 0 && (strip[0].Path != t.Path || stripHeight+b.Dy()*stripWidth/b.Dx() > e.Image.LongStripHeight) {
+					flush()
+				}
+				if len(strip) == 0 {
+					stripWidth = b.Dx()
+					if e.Image.Resize {
+						stripWidth = e.Image.View.Width
+					}
+				}
+				strip = append(strip, t)
+				stripHeight += b.Dy() * stripWidth / b.Dx()
+			}
+		}
+		flush()
+
+		// pages not received, can only happen with a missing id
+		for _, t := range pending {
+			output <- t
+		}
+	}()
+	return output
+}
+
+// copy the cover at its place in the pages, the next pages are shifted by one.
+//
+// the pages are put back in order to be renumbered.
+func (e *EPUBImageProcessor) copyCover(input chan *tasks) chan *tasks {
+	output := make(chan *tasks, e.Workers)
+	go func() {
+		defer close(output)
+
+		var cover *tasks
+		shift := 0
+		pending := make(map[int]*tasks)
+		next := 0
+		for t := range input {
+			if t.Id < 0 {
+				output <- t
+				continue
+			}
+			pending[t.Id] = t
+			for {
+				t, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				if t.Id == 0 {
+					cover = t
+				} else if cover != nil && shift == 0 && t.Id == e.coverCopyBeforeId {
+					c := *cover
+					c.Id = t.Id
+					output <- &c
+					shift = 1
+				}
+				t.Id += shift
+				output <- t
+			}
+		}
+
+		// pages not received, can only happen with a missing id
+		for _, t := range pending {
+			t.Id += shift
+			output <- t
+		}
+		if cover != nil && shift == 0 {
+			c := *cover
+			c.Id = next
+			output <- &c
+		}
+	}()
+	return output
+}
+
+// drop the unreadable images with a warning, the next pages take their ids so the numbering has no gap.
+//
+// the pages are put back in order to be renumbered. A failure without page (id -1) is kept to stop the conversion.
+func (e *EPUBImageProcessor) skipBad(input chan *tasks) chan *tasks {
+	output := make(chan *tasks, e.Workers)
+	go func() {
+		defer close(output)
+
+		unreadable := 0
+		pending := make(map[int]*tasks)
+		next := 0
+		for t := range input {
+			if t.Id < 0 {
+				output <- t
+				continue
+			}
+			pending[t.Id] = t
+			for {
+				t, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				if t.Error != nil {
+					fmt.Fprintf(os.Stderr, "\nskipping unreadable image %s: %s\n", filepath.Join(t.Path, t.Name), t.Error)
+					unreadable++
+					atomic.AddInt64(&e.unreadable, 1)
+					continue
+				}
+				t.Id -= unreadable
+				output <- t
+			}
+		}
+
+		// pages not received, can only happen with a missing id
+		for _, t := range pending {
+			t.Id -= unreadable
+			output <- t
+		}
+	}()
+	return output
+}
+
+// draw the pages of the strip one below the other at the width of the strip
+func (e *EPUBImageProcessor) stitchStrip(strip []*tasks, width, height int) *tasks {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	y := 0
+	for _, t := range strip {
+		src := t.Image
+		if src.Bounds().Dx() != width {
+			g := gift.New(gift.Resize(width, 0, e.resampling(src.Bounds())))
+			resized := image.NewRGBA(g.Bounds(src.Bounds()))
+			g.Draw(resized, src)
+			src = resized
+		}
+		h := src.Bounds().Dy()
+		if y+h > height {
+			h = height - y
+		}
+		draw.Draw(dst, image.Rect(0, y, width, y+h), src, src.Bounds().Min, draw.Src)
+		y += h
+	}
+
+	// the strip is kept whole
+	doublePage := false
+	return &tasks{
+		Id:         strip[0].Id,
+		Image:      dst,
+		Path:       strip[0].Path,
+		Name:       strip[0].Name,
+		DoublePage: &doublePage,
+		Pages:      len(strip),
+	}
+}
+
+// straighten the scan before looking for the margins
+func (e *EPUBImageProcessor) deskew(src image.Image) image.Image {
+	if !e.Image.Deskew {
+		return src
+	}
+	angle := epubimagefilters.DeskewAngle(src)
+	if angle == 0 {
+		return src
+	}
+	g := gift.New(epubimagefilters.Deskew(angle, color.White))
+	dst := image.NewRGBA(g.Bounds(src.Bounds()))
+	g.Draw(dst, src)
+	return dst
+}
+
+// margins of the image to remove, and if the image is blank
+func (e *EPUBImageProcessor) cropFilter(src image.Image) (f gift.Filter, isBlank bool) {
+	firstPageCrop := e.firstPageCropFilter(src)
+	if firstPageCrop == nil || e.Image.NoBlankImage {
+		f = epubimagefilters.AutoCrop(
 			src,
+			e.Image.Crop.Threshold,
+			e.Image.Crop.MinRatio,
 			e.Image.Crop.Left,
 			e.Image.Crop.Up,
 			e.Image.Crop.Right,
@@ -184,34 +823,93 @@ func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int) []image.
 
 		// detect if blank image
 		size := f.Bounds(src.Bounds())
-		isBlank := size.Dx() == 0 && size.Dy() == 0
+		isBlank = size.Dx() == 0 && size.Dy() == 0
+	}
+	if firstPageCrop != nil && !isBlank {
+		f = firstPageCrop
+	}
+	return
+}
+
+// add the cleaned image (straighten and cropped) at its original resolution to the archive.
+//
+// the pages are named by their index, padded to the number of pages: two pages of the same name in different
+// directories stay distinct, and the readers sort them in the order of the book.
+// the blank images are skipped with noblankimage.
+func (e *EPUBImageProcessor) archiveImage(archive *epubzip.EPUBZipStorageImageWriter, input *tasks, src image.Image, total int) error {
+	dst := src
+	if e.Image.Crop.Enabled || e.Image.NoBlankImage {
+		f, isBlank := e.cropFilter(src)
+		if isBlank && e.Image.NoBlankImage {
+			return nil
+		}
+		if e.Image.Crop.Enabled {
+			g := gift.New(f)
+			cropped := image.NewRGBA(g.Bounds(src.Bounds()))
+			g.Draw(cropped, src)
+			dst = cropped
+		}
+	}
+
+	name := fmt.Sprintf("%0*d.jpg", len(fmt.Sprint(total)), input.Id)
+	return archive.Add(name, dst, e.Image.Quality)
+}
+
+// transform image into 1 or 3 images
+// only doublepage with autosplit has 3 versions
+func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int, doublePage bool) []image.Image {
+	var filters, splitFilters []gift.Filter
+	var images []image.Image
+
+	// Lookup for margin if crop is enable or if we want to remove blank image
+	if e.Image.Crop.Enabled || e.Image.NoBlankImage {
+		f, isBlank := e.cropFilter(src)
 
 		// crop is enable or if blank image with noblankimage options
 		if e.Image.Crop.Enabled || (e.Image.NoBlankImage && isBlank) {
 			filters = append(filters, f)
 			splitFilters = append(splitFilters, f)
 		}
-	}
 
-	if e.Image.AutoRotate && src.Bounds().Dx() > src.Bounds().Dy() {
-		filters = append(filters, gift.Rotate90())
+		// margin around the cropped page, before the resize which fits the page with it
+		if e.Image.Crop.Enabled && e.Image.Crop.Padding > 0 && !isBlank {
+			f := epubimagefilters.Pad(e.Image.Crop.Padding, e.backgroundColor())
+			filters = append(filters, f)
+			splitFilters = append(splitFilters, f)
+		}
 	}
 
-	if e.Image.Contrast != 0 {
-		f := gift.Contrast(float32(e.Image.Contrast))
-		filters = append(filters, f)
-		splitFilters = append(splitFilters, f)
+	if e.Image.AutoRotate && doublePage {
+		filters = append(filters, gift.Rotate90())
 	}
 
-	if e.Image.Brightness != 0 {
-		f := gift.Brightness(float32(e.Image.Brightness))
-		filters = append(filters, f)
-		splitFilters = append(splitFilters, f)
+	if e.Image.HasCover && srcId == 0 && (e.Image.CoverFit != "fit" || e.Image.CoverRatio > 0) {
+		mode, aspectRatio := e.Image.CoverFit, float64(e.Image.View.Height)/float64(e.Image.View.Width)
+		if e.Image.CoverRatio > 0 {
+			aspectRatio = e.Image.CoverRatio
+			if mode == "fit" {
+				mode = "pad"
+			}
+		}
+		filters = append(filters, epubimagefilters.CoverFit(mode, aspectRatio, e.backgroundColor()))
 	}
 
 	if e.Image.Resize {
-		f := gift.ResizeToFit(e.Image.View.Width, e.Image.View.Height, gift.LanczosResampling)
-		filters = append(filters, f)
+		height := e.Image.View.Height
+		if e.Image.LongStrip && !(e.Image.HasCover && srcId == 0) {
+			height = e.Image.LongStripHeight
+		}
+		if e.Image.FitMode == "cover" {
+			filters = append(filters, e.coverFitFilter())
+		}
+		bounds := gift.New(filters...).Bounds(src.Bounds())
+
+		// low-pass before the reduction, only for the pages reduced
+		if ratio := math.Max(float64(bounds.Dx())/float64(e.Image.View.Width), float64(bounds.Dy())/float64(height)); e.Image.Descreen && ratio > 1 {
+			filters = append(filters, epubimagefilters.Descreen(ratio))
+		}
+
+		filters = append(filters, e.resizeFilters(e.Image.View.Width, height, bounds)...)
 	}
 
 	if e.Image.GrayScale {
@@ -222,11 +920,18 @@ func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int) []image.
 				y := (r0 + g0 + b0) / 3
 				return y, y, y, a0
 			})
-		case 2: // luminance
+		case 2: // luminance, Rec. 709 luma
 			f = gift.ColorFunc(func(r0, g0, b0, a0 float32) (r float32, g float32, b float32, a float32) {
 				y := 0.2126*r0 + 0.7152*g0 + 0.0722*b0
 				return y, y, y, a0
 			})
+		case 3: // colorblind
+			// the red and green looking the same to a deuteranope or a protanope have a close luminance,
+			// weighting the red over the green turn them into distinct grays.
+			f = gift.ColorFunc(func(r0, g0, b0, a0 float32) (r float32, g float32, b float32, a float32) {
+				y := 0.55*r0 + 0.2*g0 + 0.25*b0
+				return y, y, y, a0
+			})
 		default:
 			f = gift.Grayscale()
 		}
@@ -234,6 +939,32 @@ func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int) []image.
 		splitFilters = append(splitFilters, f)
 	}
 
+	// the edges softened by the resize, on the pixels of the output
+	if e.Image.Sharpen {
+		filters = append(filters, e.sharpenFilter())
+	}
+
+	// after the resize and the grayscale, the levels are the ones of the output
+	var levelFilters []gift.Filter
+	if e.Image.AutoContrast {
+		levelFilters = append(levelFilters, epubimagefilters.AutoContrast(e.Image.AutoContrastClip))
+	}
+
+	// the manual readjustement, after the autocontrast to not be undone by it
+	if e.Image.Brightness != 0 || e.Image.Contrast != 0 {
+		levelFilters = append(levelFilters, epubimagefilters.BrightnessContrast(e.Image.Brightness, e.Image.Contrast))
+	}
+
+	if e.Image.Gamma != 1 {
+		levelFilters = append(levelFilters, gift.Gamma(float32(e.Image.Gamma)))
+	}
+	filters = append(filters, levelFilters...)
+
+	// last, the levels of the device are the final ones
+	if e.Image.GrayScale && e.Image.Dither {
+		filters = append(filters, epubimagefilters.Dither(e.Image.DitherLevels))
+	}
+
 	filters = append(filters, epubimagefilters.Pixel())
 
 	// convert
@@ -245,12 +976,12 @@ func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int) []image.
 	}
 
 	// auto split off
-	if !e.Image.AutoSplitDoublePage {
+	if !e.Image.AutoSplitDoublePage || e.Image.SpreadMode == "keep" {
 		return images
 	}
 
 	// portrait, no need to split
-	if src.Bounds().Dx() <= src.Bounds().Dy() {
+	if !doublePage {
 		return images
 	}
 
@@ -264,7 +995,23 @@ func (e *EPUBImageProcessor) transformImage(src image.Image, srcId int) []image.
 		g := gift.New(splitFilters...)
 		g.Add(epubimagefilters.CropSplitDoublePage(b))
 		if e.Image.Resize {
-			g.Add(gift.ResizeToFit(e.Image.View.Width, e.Image.View.Height, gift.LanczosResampling))
+			if e.Image.FitMode == "cover" {
+				g.Add(e.coverFitFilter())
+			}
+			bounds := g.Bounds(src.Bounds())
+			// the half is reduced less than the whole spread
+			if ratio := math.Max(float64(bounds.Dx())/float64(e.Image.View.Width), float64(bounds.Dy())/float64(e.Image.View.Height)); e.Image.Descreen && ratio > 1 {
+				g.Add(epubimagefilters.Descreen(ratio))
+			}
+			g.Add(e.resizeFilters(e.Image.View.Width, e.Image.View.Height, bounds)...)
+		}
+		if e.Image.Sharpen {
+			g.Add(e.sharpenFilter())
+		}
+		// the levels of the half, not of the whole spread
+		g.Add(levelFilters...)
+		if e.Image.GrayScale && e.Image.Dither {
+			g.Add(epubimagefilters.Dither(e.Image.DitherLevels))
 		}
 		dst := e.createImage(src, g.Bounds(src.Bounds()))
 		g.Draw(dst, src)
@@ -318,10 +1065,36 @@ func (e *EPUBImageProcessor) CoverTitleData(o *CoverTitleDataOptions) (*epubzip.
 	}
 	g.Draw(dst, o.Src)
 
+	format := e.Image.Format
+	if o.Name == "cover" {
+		format = e.Image.CoverFormat()
+	}
+
 	return epubzip.CompressImage(
-		fmt.Sprintf("OEBPS/Images/%s.%s", o.Name, e.Image.Format),
-		e.Image.Format,
+		fmt.Sprintf("OEBPS/Images/%s.%s", o.Name, format),
+		format,
 		dst,
 		e.Image.Quality,
+		e.Image.Lossless,
 	)
 }
+
+// create the thumbnail of a converted image for the toc
+func (e *EPUBImageProcessor) Thumbnail(f *zip.File, name string) (*epubzip.ZipImage, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	g := gift.New(gift.ResizeToFit(e.Image.View.Width/8, e.Image.View.Height/8, gift.LanczosResampling))
+	dst := e.createImage(src, g.Bounds(src.Bounds()))
+	g.Draw(dst, src)
+
+	return epubzip.CompressImage(name, e.Image.Format, dst, e.Image.Quality, e.Image.Lossless)
+}
@@ -0,0 +1,69 @@
+package epubimageprocessor
+
+import (
+	"archive/zip"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+func TestArchiveNames(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input")
+	// the same names in each chapter
+	for _, name := range []string{"ch1/01.jpg", "ch1/02.jpg", "ch2/01.jpg", "ch2/02.jpg"} {
+		path := filepath.Join(input, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := jpeg.Encode(f, image.NewGray(image.Rect(0, 0, 60, 80)), nil); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	archive := filepath.Join(dir, "archive.cbz")
+	e := New(&epuboptions.Options{
+		Input:      input,
+		Output:     filepath.Join(dir, "comic.epub"),
+		ArchiveCbz: archive,
+		Workers:    2,
+		Quiet:      true,
+		Image: &epuboptions.Image{
+			Crop:    &epuboptions.Crop{},
+			Quality: 85,
+			Gamma:   1,
+			Format:  "jpeg",
+			View:    &epuboptions.View{Width: 100, Height: 100},
+		},
+	})
+	if _, err := e.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	names := map[string]bool{}
+	for _, f := range r.File {
+		names[f.Name] = true
+	}
+	for _, want := range []string{"0.jpg", "1.jpg", "2.jpg", "3.jpg"} {
+		if !names[want] {
+			t.Errorf("pages %v, want %s", names, want)
+		}
+	}
+	if len(names) != 4 {
+		t.Errorf("%d pages, want 4", len(names))
+	}
+}
@@ -0,0 +1,116 @@
+package epubimageprocessor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+)
+
+// verify that every entry of a zip, rar or 7z input can be read and match its checksum, before the conversion.
+//
+// the other inputs are not checked. The corrupted entries are listed in the error.
+func (e *EPUBImageProcessor) VerifyArchive() error {
+	var corrupted []string
+	var err error
+	switch inputExt(e.Input) {
+	case ".cbz", ".zip":
+		corrupted, err = verifyZip(e.Input, e.Password)
+	case ".cbr", ".rar":
+		corrupted, err = verifyRar(rarFirstVolume(e.Input), e.Password)
+	case ".cb7", ".7z":
+		corrupted, err = verify7z(e.Input)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("corrupted archive %s: %w", e.Input, err)
+	}
+	if len(corrupted) > 0 {
+		return fmt.Errorf("corrupted archive %s, %d entries:\n  %s", e.Input, len(corrupted), strings.Join(corrupted, "\n  "))
+	}
+	return nil
+}
+
+// the zip reader check the crc32 at the end of each entry
+func verifyZip(input string, password string) (corrupted []string, err error) {
+	files, r, err := openZip(input, password)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	for _, f := range files {
+		if f.IsDir {
+			continue
+		}
+		if ferr := verifyEntry(f.Open); ferr != nil {
+			corrupted = append(corrupted, fmt.Sprintf("%s: %s", f.Name, ferr))
+		}
+	}
+	return
+}
+
+// the 7z reader check the crc32 at the end of each entry
+func verify7z(input string) (corrupted []string, err error) {
+	r, err := sevenzip.OpenReader(input)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if ferr := verifyEntry(f.Open); ferr != nil {
+			corrupted = append(corrupted, fmt.Sprintf("%s: %s", f.Name, ferr))
+		}
+	}
+	return
+}
+
+// the rar reader check the checksum at the end of each entry, a truncated archive stop the listing
+func verifyRar(input string, password string) (corrupted []string, err error) {
+	r, err := rardecode.OpenReader(input, rarOptions(password)...)
+	if err != nil {
+		err = rarError(input, err)
+		return
+	}
+	defer r.Close()
+
+	for entries := 0; ; entries++ {
+		f, ferr := r.Next()
+		if ferr == io.EOF {
+			return
+		}
+		if ferr != nil {
+			corrupted = append(corrupted, fmt.Sprintf("after %d entries: %s", entries, rarError(input, ferr)))
+			return
+		}
+		if f.IsDir {
+			continue
+		}
+		if _, ferr = io.Copy(io.Discard, r); ferr != nil {
+			corrupted = append(corrupted, fmt.Sprintf("%s: %s", f.Name, rarError(input, ferr)))
+			// the next volume is missing, nothing more can be read
+			if errors.Is(ferr, fs.ErrNotExist) {
+				return
+			}
+		}
+	}
+}
+
+func verifyEntry(open func() (io.ReadCloser, error)) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
@@ -0,0 +1,82 @@
+package epubimageprocessor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+// names of the pages in the order of their ids
+func pageOrder(images []*epubimage.Image) []string {
+	sort.Slice(images, func(i, j int) bool { return images[i].Id < images[j].Id })
+	names := make([]string, len(images))
+	for i, img := range images {
+		names[i] = filepath.Join(img.Path, img.Name)
+	}
+	return names
+}
+
+func TestCoverCopyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"ch1/page01.jpg", "ch1/page02.jpg", "ch1/page03.jpg", "ch2/page04.jpg"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := New(&epuboptions.Options{
+		Input:         dir,
+		Dry:           true,
+		Workers:       2,
+		CoverSelector: "page02.jpg",
+		Image:         &epuboptions.Image{HasCover: true, Format: "jpeg"},
+	})
+	images, err := e.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ch1/page02.jpg", "ch1/page01.jpg", "ch1/page02.jpg", "ch1/page03.jpg", "ch2/page04.jpg"}
+	got := pageOrder(images)
+	if len(got) != len(want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != filepath.FromSlash(want[i]) {
+			t.Fatalf("pages = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCoverFileAtRoot(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		names []string
+		first string
+	}{
+		{"root", []string{"/in/ch1/01.jpg", "/in/cover.jpg"}, "/in/cover.jpg"},
+		{"chapter", []string{"/in/ch1/01.jpg", "/in/ch1/folder.jpg", "/in/ch2/01.jpg"}, "/in/ch1/01.jpg"},
+		{"wrapping directory", []string{"Comic/ch1/01.jpg", "Comic/folder.jpg"}, "Comic/folder.jpg"},
+		{"archive root", []string{"ch1/01.jpg", "ch1/cover.jpg", "ch2/01.jpg"}, "ch1/01.jpg"},
+	} {
+		e := New(&epuboptions.Options{Image: &epuboptions.Image{HasCover: true}})
+		names, err := e.selectNames(c.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names[0] != c.first {
+			t.Errorf("%s: cover %s, want %s", c.name, names[0], c.first)
+		}
+		if len(names) != len(c.names) {
+			t.Errorf("%s: %d pages, want %d", c.name, len(names), len(c.names))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package epubimageprocessor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+const (
+	// pages voting for the direction, spread among the first pages to skip the credits
+	directionPages  = 16
+	directionWindow = 48
+	// below, the page has no visible binding (digital source, cleaned scan)
+	gutterMinDiff = 8
+	// votes needed, and part of them that agree
+	directionMinVotes = 4
+	directionMinAgree = 0.5
+)
+
+// guess the reading direction from the binding of the scanned pages, when the ComicInfo doesn't give it.
+//
+// the binding of a scan is shadowed, so the darker side edge is the gutter. In a book read left to right,
+// the gutter of the odd pages (the cover is page 1) is on the left and the one of the even pages on the right,
+// it's the opposite for a manga. Each sampled page votes, and the direction is only changed if enough pages
+// have a visible gutter and most of them agree. Otherwise -manga is kept.
+func (e *EPUBImageProcessor) detectDirection(input chan *tasks, total int) chan *tasks {
+	ids := e.sampleIds(total, directionPages, directionWindow)
+	sampled, output := e.collect(input, ids)
+
+	votes, ltr := 0, 0
+	for _, id := range ids {
+		t, ok := sampled[id]
+		if !ok || t.Error != nil || t.Image == nil {
+			continue
+		}
+		side := gutterSide(t.Image, e.Image.DoublePageRatio)
+		if side == 0 {
+			continue
+		}
+		votes++
+		// left to right: page 1 (id 0) has its gutter on the left
+		if (id%2 == 0) == (side < 0) {
+			ltr++
+		} else {
+			ltr--
+		}
+	}
+
+	if votes >= directionMinVotes && float64(abs(ltr))/float64(votes) >= directionMinAgree {
+		e.Image.Manga = ltr < 0
+		if !e.Quiet {
+			direction := "left to right"
+			if e.Image.Manga {
+				direction = "right to left (manga)"
+			}
+			fmt.Fprintf(os.Stderr, "Direction detected from the gutter of %d pages: %s\n", votes, direction)
+		}
+	}
+	return output
+}
+
+// side of the gutter of a page: -1 left, 1 right, 0 not visible or a double page.
+func gutterSide(img image.Image, doublePageRatio float64) int {
+	b := img.Bounds()
+	if float64(b.Dx()) > float64(b.Dy())*doublePageRatio {
+		return 0
+	}
+	strip := b.Dx() / 20
+	if strip < 1 {
+		return 0
+	}
+	left := meanGray(img, image.Rect(b.Min.X, b.Min.Y, b.Min.X+strip, b.Max.Y))
+	right := meanGray(img, image.Rect(b.Max.X-strip, b.Min.Y, b.Max.X, b.Max.Y))
+	switch {
+	case right-left >= gutterMinDiff:
+		return -1
+	case left-right >= gutterMinDiff:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// average gray of an area, one pixel out of 4 in each direction
+func meanGray(img image.Image, r image.Rectangle) float64 {
+	sum, n := 0, 0
+	for y := r.Min.Y; y < r.Max.Y; y += 4 {
+		for x := r.Min.X; x < r.Max.X; x += 4 {
+			sum += int(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(sum) / float64(n)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
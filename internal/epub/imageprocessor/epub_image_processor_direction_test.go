@@ -0,0 +1,77 @@
+package epubimageprocessor
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+// white page with a shadow along one side: -1 left, 1 right, 0 none
+func scanPage(gutter int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 200, 300))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	shadow := image.Rect(0, 0, 10, 300)
+	if gutter > 0 {
+		shadow = image.Rect(190, 0, 200, 300)
+	}
+	if gutter != 0 {
+		draw.Draw(img, shadow, image.NewUniform(color.Gray{0x80}), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestGutterSide(t *testing.T) {
+	for _, side := range []int{-1, 0, 1} {
+		if got := gutterSide(scanPage(side), 1); got != side {
+			t.Errorf("gutterSide = %d, want %d", got, side)
+		}
+	}
+	spread := image.NewGray(image.Rect(0, 0, 400, 300))
+	if got := gutterSide(spread, 1); got != 0 {
+		t.Errorf("gutterSide of a double page = %d, want 0", got)
+	}
+}
+
+// gutter of a page read left to right: odd pages (even ids) on the left
+func ltrGutter(id int) int {
+	if id%2 == 0 {
+		return -1
+	}
+	return 1
+}
+
+func TestDetectDirection(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		gutter func(id int) int
+		manga  bool
+		want   bool
+	}{
+		{"left to right", ltrGutter, true, false},
+		{"manga", func(id int) int { return -ltrGutter(id) }, false, true},
+		{"no gutter keeps -manga", func(id int) int { return 0 }, true, true},
+	} {
+		input := make(chan *tasks)
+		go func() {
+			defer close(input)
+			for id := 0; id < 20; id++ {
+				input <- &tasks{Id: id, Image: scanPage(c.gutter(id))}
+			}
+		}()
+
+		e := New(&epuboptions.Options{Workers: 1, Quiet: true, Image: &epuboptions.Image{HasCover: true, Manga: c.manga, DoublePageRatio: 1}})
+		n := 0
+		for range e.detectDirection(input, 20) {
+			n++
+		}
+		if n != 20 {
+			t.Errorf("%s: %d pages replayed, want 20", c.name, n)
+		}
+		if e.Image.Manga != c.want {
+			t.Errorf("%s: manga = %v, want %v", c.name, e.Image.Manga, c.want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package epubimageprocessor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+	"github.com/disintegration/gift"
+)
+
+// grayscale processor that keeps the size and the margins of the page
+func filterProcessor() *EPUBImageProcessor {
+	return New(&epuboptions.Options{Image: &epuboptions.Image{
+		Crop:      &epuboptions.Crop{},
+		GrayScale: true,
+		Gamma:     1,
+		View:      &epuboptions.View{Width: 100, Height: 100},
+	}})
+}
+
+// horizontal ramp of all the levels of gray
+func ramp() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 256, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 256; x++ {
+			img.SetGray(x, y, color.Gray{uint8(x)})
+		}
+	}
+	return img
+}
+
+func TestGamma(t *testing.T) {
+	src := ramp()
+	identity := filterProcessor().transformImage(src, 1, false)[0].(*image.Gray)
+	if !bytes.Equal(identity.Pix, src.Pix) {
+		t.Error("gamma 1 changed the pixels")
+	}
+
+	// the midtones are lightened above 1 and darkened below, black and white are kept
+	for _, c := range []struct {
+		gamma   float64
+		lighter bool
+	}{{1.8, true}, {0.5, false}} {
+		e := filterProcessor()
+		e.Image.Gamma = c.gamma
+		dst := e.transformImage(src, 1, false)[0].(*image.Gray)
+		if dst.GrayAt(0, 0).Y != 0 || dst.GrayAt(255, 0).Y != 0xff {
+			t.Errorf("gamma %v: black %d and white %d", c.gamma, dst.GrayAt(0, 0).Y, dst.GrayAt(255, 0).Y)
+		}
+		if mid := dst.GrayAt(128, 0).Y; (mid > 128) != c.lighter {
+			t.Errorf("gamma %v: middle gray %d", c.gamma, mid)
+		}
+	}
+}
+
+// dark half beside a light half, reduced by half to the view
+func edge() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			v := uint8(0x40)
+			if x >= 100 {
+				v = 0xc0
+			}
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	return img
+}
+
+func TestSharpen(t *testing.T) {
+	plain, sharpened := filterProcessor(), filterProcessor()
+	for _, e := range []*EPUBImageProcessor{plain, sharpened} {
+		e.Image.Resize = true
+		e.Image.ResizeFilter = "lanczos"
+	}
+	sharpened.Image.Sharpen = true
+	sharpened.Image.SharpenAmount = 1
+
+	before := plain.transformImage(edge(), 1, false)[0].(*image.Gray)
+	after := sharpened.transformImage(edge(), 1, false)[0].(*image.Gray)
+	if before.Bounds() != image.Rect(0, 0, 100, 50) || after.Bounds() != before.Bounds() {
+		t.Fatalf("sizes %v and %v, want 100x50", before.Bounds(), after.Bounds())
+	}
+
+	// the unsharp mask darkens the dark side and lightens the light side along the edge
+	y := 25
+	if after.GrayAt(49, y).Y >= before.GrayAt(49, y).Y || after.GrayAt(50, y).Y <= before.GrayAt(50, y).Y {
+		t.Errorf("edge %d|%d before, %d|%d after", before.GrayAt(49, y).Y, before.GrayAt(50, y).Y, after.GrayAt(49, y).Y, after.GrayAt(50, y).Y)
+	}
+	// far from the edge the flat areas are kept
+	if before.GrayAt(10, y).Y != after.GrayAt(10, y).Y || before.GrayAt(90, y).Y != after.GrayAt(90, y).Y {
+		t.Errorf("flat areas %d %d before, %d %d after", before.GrayAt(10, y).Y, before.GrayAt(90, y).Y, after.GrayAt(10, y).Y, after.GrayAt(90, y).Y)
+	}
+}
+
+func TestDitherPage(t *testing.T) {
+	e := filterProcessor()
+	e.Image.Dither = true
+	e.Image.DitherLevels = 4
+
+	// the levels of the device are the last ones, after the other filters
+	e.Image.Gamma = 1.2
+	for _, p := range e.transformImage(ramp(), 1, false)[0].(*image.Gray).Pix {
+		if p%0x55 != 0 {
+			t.Fatalf("gray %d not in the 4 levels", p)
+		}
+	}
+}
+
+func TestGrayscaleLuminance(t *testing.T) {
+	e := filterProcessor()
+	e.Image.GrayScaleMode = 2
+
+	colors := []color.RGBA{
+		{0xff, 0, 0, 0xff},
+		{0, 0xff, 0, 0xff},
+		{0, 0, 0xff, 0xff},
+		{0xff, 0xff, 0xff, 0xff},
+		{0x80, 0x40, 0x20, 0xff},
+	}
+	src := image.NewRGBA(image.Rect(0, 0, len(colors), 1))
+	for x, c := range colors {
+		src.SetRGBA(x, 0, c)
+	}
+
+	dst := e.transformImage(src, 1, false)[0].(*image.Gray)
+	for x, c := range colors {
+		want := 0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)
+		if got := float64(dst.GrayAt(x, 0).Y); got < want-1 || got > want+1 {
+			t.Errorf("%v: gray %v, want %.1f", c, got, want)
+		}
+	}
+}
+
+// white page with a black panel
+func borderedPage(panel image.Rectangle) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 200, 300))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := panel.Min.Y; y < panel.Max.Y; y++ {
+		for x := panel.Min.X; x < panel.Max.X; x++ {
+			img.SetGray(x, y, color.Gray{})
+		}
+	}
+	return img
+}
+
+// box of the dark pixels
+func contentBox(img *image.Gray) image.Rectangle {
+	var box image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.GrayAt(x, y).Y < 0x80 {
+				box = box.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return box
+}
+
+func TestCropPadding(t *testing.T) {
+	panel := image.Rect(50, 60, 150, 240)
+	for _, c := range []struct {
+		resize        bool
+		bounds, inner image.Rectangle
+	}{
+		// the detected box grown by the padding on each side
+		{false, image.Rect(0, 0, 120, 200), image.Rect(10, 10, 110, 190)},
+		// the padding is part of the page fitted to the view
+		{true, image.Rect(0, 0, 60, 100), image.Rect(5, 5, 55, 95)},
+	} {
+		e := filterProcessor()
+		e.Image.Crop = &epuboptions.Crop{Enabled: true, Threshold: 0xe0, Padding: 10}
+		e.Image.Resize = c.resize
+		e.Image.ResizeFilter = "lanczos"
+		e.Image.View = &epuboptions.View{Width: 60, Height: 100}
+
+		dst := e.transformImage(borderedPage(panel), 1, false)[0].(*image.Gray)
+		if dst.Bounds() != c.bounds {
+			t.Errorf("resize %v: page %v, want %v", c.resize, dst.Bounds(), c.bounds)
+		}
+		if box := contentBox(dst); box != c.inner {
+			t.Errorf("resize %v: content %v, want %v", c.resize, box, c.inner)
+		}
+		// the margin has the background color
+		if p := dst.GrayAt(1, 1).Y; p != 0xff {
+			t.Errorf("resize %v: margin %d", c.resize, p)
+		}
+	}
+}
+
+func TestBoxRatio(t *testing.T) {
+	e := filterProcessor()
+	e.Image.ResizeFilter = "bilinear"
+	e.Image.BoxRatio = 4
+	for _, c := range []struct {
+		width int
+		want  gift.Resampling
+	}{
+		{300, gift.LinearResampling},
+		{399, gift.LinearResampling},
+		{400, gift.BoxResampling},
+		{4000, gift.BoxResampling},
+	} {
+		// the resamplings are not comparable, the support tells them apart
+		if got := e.resampling(image.Rect(0, 0, c.width, c.width)); got.Support() != c.want.Support() {
+			t.Errorf("reduction of %d to 100: support %v, want %v", c.width, got.Support(), c.want.Support())
+		}
+	}
+}
+
+// page of fine text, lines of 1 pixel every 3 pixels, and the exact average of each block of the reduction
+func fineText(width, height, ratio int) (src, want *image.Gray) {
+	src = image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x%3 != 0 && y%3 != 0 {
+				src.SetGray(x, y, color.Gray{0xff})
+			}
+		}
+	}
+	want = image.NewGray(image.Rect(0, 0, width/ratio, height/ratio))
+	for y := 0; y < height/ratio; y++ {
+		for x := 0; x < width/ratio; x++ {
+			sum := 0
+			for j := 0; j < ratio; j++ {
+				for i := 0; i < ratio; i++ {
+					sum += int(src.GrayAt(x*ratio+i, y*ratio+j).Y)
+				}
+			}
+			want.SetGray(x, y, color.Gray{uint8(sum / (ratio * ratio))})
+		}
+	}
+	return
+}
+
+// the legibility of a 4000px scan reduced to 600px, reported as the mean error to the exact average of the blocks:
+// the aliasing of the point and linear sampling shows as a bigger error.
+func BenchmarkResizeReduction(b *testing.B) {
+	const ratio = 8
+	src, want := fineText(600*ratio, 900*ratio, ratio)
+	for _, c := range []struct {
+		name     string
+		filter   string
+		boxRatio float64
+	}{
+		{"nearest", "nearest", 0},
+		{"bilinear", "bilinear", 0},
+		{"lanczos", "lanczos", 0},
+		{"boxratio", "lanczos", 4},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			e := filterProcessor()
+			e.Image.Resize = true
+			e.Image.ResizeFilter = c.filter
+			e.Image.BoxRatio = c.boxRatio
+			e.Image.View = &epuboptions.View{Width: 600, Height: 900}
+
+			var dst *image.Gray
+			for i := 0; i < b.N; i++ {
+				dst = e.transformImage(src, 1, false)[0].(*image.Gray)
+			}
+
+			diff := 0
+			for i, p := range dst.Pix {
+				diff += max(int(p), int(want.Pix[i])) - min(int(p), int(want.Pix[i]))
+			}
+			b.ReportMetric(float64(diff)/float64(len(dst.Pix)), "error/px")
+		})
+	}
+}
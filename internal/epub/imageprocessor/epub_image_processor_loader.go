@@ -1,23 +1,39 @@
 package epubimageprocessor
 
 import (
-	"archive/zip"
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"image"
+	"image/draw"
+	_ "image/gif"
 	_ "image/jpeg"
-	_ "image/png"
+	"image/png"
 	"io"
 	"io/fs"
+	"math"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/disintegration/gift"
+	_ "github.com/gen2brain/avif"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
 
+	"github.com/bodgit/sevenzip"
+	"github.com/celogeek/go-comic-converter/v2/internal/comicinfo"
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+	"github.com/celogeek/go-comic-converter/v2/internal/iccprofile"
+	"github.com/celogeek/go-comic-converter/v2/internal/jpegscale"
 	"github.com/celogeek/go-comic-converter/v2/internal/sortpath"
 	"github.com/nwaples/rardecode/v2"
 	pdfimage "github.com/raff/pdfreader/image"
@@ -25,18 +41,42 @@ import (
 )
 
 type tasks struct {
-	Id    int
-	Image image.Image
-	Path  string
-	Name  string
+	Id         int
+	Image      image.Image
+	Jpeg       []byte
+	Path       string
+	Name       string
+	DoublePage *bool
+	Error      error
+	Rendered   bool // page created by the converter, kept as is
+	Pages      int  // number of pages concatenated in a long strip
+}
+
+// double page marker from the ComicInfo take precedence over the aspect ratio
+//
+// the page is a double page when its width is greater than its height by the ratio (1 if not set).
+func (t *tasks) isDoublePage(ratio float64) bool {
+	if t.DoublePage != nil {
+		return *t.DoublePage
+	}
+	if ratio < 1 {
+		ratio = 1
+	}
+	return float64(t.Image.Bounds().Dx()) > float64(t.Image.Bounds().Dy())*ratio
 }
 
 var errNoImagesFound = errors.New("no images found")
+var errNoPagesKept = errors.New("no pages left with the keep pages")
 
-// only accept jpg, png and webp as source file
+// only accept jpg, png, webp, avif, gif, bmp and tiff as source file
 func (e *EPUBImageProcessor) isSupportedImage(path string) bool {
+	return IsSupportedImage(path)
+}
+
+// Check if the extension of the file is a supported source image.
+func IsSupportedImage(path string) bool {
 	switch strings.ToLower(filepath.Ext(path)) {
-	case ".jpg", ".jpeg", ".png", ".webp":
+	case ".jpg", ".jpeg", ".png", ".webp", ".avif", ".gif", ".bmp", ".tif", ".tiff":
 		{
 			return true
 		}
@@ -44,6 +84,380 @@ func (e *EPUBImageProcessor) isSupportedImage(path string) bool {
 	return false
 }
 
+// Check if the extension of the file is a supported input other than a directory: an archive, a pdf or a djvu.
+func IsSupportedInput(path string) bool {
+	switch inputExt(path) {
+	case ".cbz", ".zip", ".cbr", ".rar", ".cb7", ".7z", ".cbt", ".tar", ".tar.gz", ".tgz", ".pdf", ".djvu", ".djv":
+		return true
+	}
+	return false
+}
+
+// detect the images without extension from their first bytes: jpeg, png, webp, avif, gif or tiff
+func (e *EPUBImageProcessor) isSniffedImage(path string, open func() (io.ReadCloser, error)) bool {
+	if !e.SniffContent || filepath.Ext(path) != "" {
+		return false
+	}
+
+	f, err := open()
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	b := make([]byte, 12)
+	n, _ := io.ReadFull(f, b)
+	b = b[:n]
+	return bytes.HasPrefix(b, []byte("\xff\xd8\xff")) ||
+		bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) ||
+		(n == 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WEBP") ||
+		(n == 12 && string(b[4:12]) == "ftypavif") ||
+		bytes.HasPrefix(b, []byte("GIF8")) ||
+		bytes.HasPrefix(b, []byte("II\x2a\x00")) ||
+		bytes.HasPrefix(b, []byte("MM\x00\x2a"))
+}
+
+// report the files skipped because they are not a supported image
+func (e *EPUBImageProcessor) warnUnsupported(name string) {
+	if e.WarnUnsupported {
+		fmt.Fprintf(os.Stderr, "skipping unsupported file %s\n", name)
+	}
+}
+
+// skip hidden files and directories unless requested,
+// and always the noise left by some os like __MACOSX or Thumbs.db.
+func (e *EPUBImageProcessor) isExcluded(path string) bool {
+	for _, p := range strings.Split(filepath.ToSlash(filepath.Clean(path)), "/") {
+		if p == "__MACOSX" || p == "Thumbs.db" {
+			return true
+		}
+		if !e.IncludeHidden && len(p) > 1 && p[0] == '.' && p != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// open and decode an image, only the header in preflight mode
+//
+// the pixels are converted to sRGB with the color profile (ICC) embedded in a jpeg, a png or a webp.
+// the original bytes of a jpeg are also returned for the raw pages.
+func (e *EPUBImageProcessor) decode(open func() (io.ReadCloser, error)) (image.Image, []byte, error) {
+	f, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	if e.Preflight {
+		c, _, err := image.DecodeConfig(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &imageConfig{c}, nil, nil
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the archive keeps the full resolution
+	if e.Image.ScaledDecode && e.Image.Resize && e.ArchiveCbz == "" && !e.Image.RawPages && jpegscale.IsJpeg(data) {
+		if img, err := jpegscale.Decode(data, e.decodeScale); err == nil {
+			return iccprofile.ToSRGB(img, data), nil, nil
+		}
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	img = iccprofile.ToSRGB(flattenGif(img, format), data)
+	if e.Image.RawPages && format == "jpeg" {
+		return img, data, nil
+	}
+	return img, nil, nil
+}
+
+// draw a gif with transparency on a white background, the e-ink readers render the transparency as black.
+//
+// only the first frame of an animated gif is decoded.
+func flattenGif(img image.Image, format string) image.Image {
+	p, ok := img.(*image.Paletted)
+	if format != "gif" || !ok {
+		return img
+	}
+	for _, c := range p.Palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			dst := image.NewRGBA(p.Bounds())
+			draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+			draw.Draw(dst, dst.Bounds(), p, p.Bounds().Min, draw.Over)
+			return dst
+		}
+	}
+	return img
+}
+
+// largest reduction of a jpeg (1, 2, 4 or 8) that keeps the page bigger than the device.
+//
+// a double page needs twice the width to be splitted, or the height of the device to be rotated.
+func (e *EPUBImageProcessor) decodeScale(width, height int) int {
+	minWidth, minHeight := e.Image.View.Width, e.Image.View.Height
+	if width > height {
+		if e.Image.AutoRotate {
+			minWidth, minHeight = minHeight, minWidth
+		} else if e.Image.AutoSplitDoublePage {
+			minWidth *= 2
+		}
+	}
+	scale := 1
+	for scale < 8 && width/(scale*2) >= minWidth && height/(scale*2) >= minHeight {
+		scale *= 2
+	}
+	return scale
+}
+
+// read the ComicInfo.xml, an invalid one is ignored
+func (e *EPUBImageProcessor) readComicInfo(name string, open func() (io.ReadCloser, error)) *comicinfo.ComicInfo {
+	f, err := open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring %s: %s\n", name, err)
+		return nil
+	}
+	defer f.Close()
+	ci, err := comicinfo.Parse(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring %s: %s\n", name, err)
+		return nil
+	}
+	return ci
+}
+
+// apply the ComicInfo pages description to the sorted names.
+//
+// the deleted pages and advertisements are removed, and the double page markers are indexed by name.
+// the reading direction is also taken from it with the auto direction, the content of the pages is then not inspected.
+func (e *EPUBImageProcessor) applyComicInfo(names []string, ci *comicinfo.ComicInfo) (kept []string, doublePages map[string]*bool) {
+	if rtl, ok := ci.RightToLeft(); ok && e.Image.AutoDirection {
+		e.Image.Manga = rtl
+		e.directionFound = true
+	}
+
+	kept = make([]string, 0, len(names))
+	doublePages = make(map[string]*bool)
+	for i, name := range names {
+		p := ci.Page(i)
+		if p.Skip() {
+			continue
+		}
+		if p != nil && p.DoublePage != nil {
+			doublePages[name] = p.DoublePage
+		}
+		kept = append(kept, name)
+	}
+	e.skipped += len(names) - len(kept)
+	return
+}
+
+// replace the sorted names by the order file if provided.
+//
+// each line is a source name relative to the input, every image should be listed exactly once.
+func (e *EPUBImageProcessor) applyOrder(names []string, rel func(string) string) ([]string, error) {
+	if e.Order == "" {
+		return names, nil
+	}
+
+	b, err := os.ReadFile(e.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	indexedNames := make(map[string]string, len(names))
+	for _, name := range names {
+		indexedNames[rel(name)] = name
+	}
+
+	ordered := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		line = filepath.ToSlash(filepath.Clean(line))
+		name, ok := indexedNames[line]
+		if !ok {
+			return nil, fmt.Errorf("order: %s not found in the input", line)
+		}
+		if seen[line] {
+			return nil, fmt.Errorf("order: %s listed more than once", line)
+		}
+		seen[line] = true
+		ordered = append(ordered, name)
+	}
+
+	missing := []string{}
+	for _, name := range names {
+		if !seen[rel(name)] {
+			missing = append(missing, rel(name))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("order: %d image(s) not listed: %s", len(missing), strings.Join(missing, ", "))
+	}
+
+	return ordered, nil
+}
+
+// index of the pages to keep in reading order.
+//
+// only the pages of the keep pages ranges are available if set.
+// the pages are evenly spaced across the available ones when sampling,
+// and starts from the end if the numbering runs backward.
+func (e *EPUBImageProcessor) selectPages(total int) ([]int, error) {
+	available := make([]int, 0, total)
+	for i := 0; i < total; i++ {
+		if e.isKeptPage(i + 1) {
+			available = append(available, i)
+		}
+	}
+	if len(available) == 0 && len(e.KeepPages) > 0 {
+		return nil, errNoPagesKept
+	}
+
+	if e.ReverseOrder {
+		for i, j := 0, len(available)-1; i < j; i, j = i+1, j-1 {
+			available[i], available[j] = available[j], available[i]
+		}
+	}
+
+	pages := epubimage.Sample(len(available), e.Sample)
+	for i, j := range pages {
+		pages[i] = available[j]
+	}
+	return pages, nil
+}
+
+// check if the page number (starting at 1) is in the keep pages ranges, all pages are kept if not set
+func (e *EPUBImageProcessor) isKeptPage(page int) bool {
+	if len(e.KeepPages) == 0 {
+		return true
+	}
+	for _, r := range e.KeepPages {
+		if page >= r[0] && (r[1] == 0 || page <= r[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// keep only the names of the selected pages.
+//
+// the cover selected with -cover, or else the cover file (cover.jpg, folder.jpg), is used as the first page if found.
+// The selected cover is also copied at its place in the pages, unless -coveronly.
+func (e *EPUBImageProcessor) selectNames(names []string) ([]string, error) {
+	cover := -1
+	if e.Image.HasCover && e.CoverSelector != "" {
+		if cover = findCover(names, e.CoverSelector); cover == -1 {
+			return nil, fmt.Errorf("cover %s not found", e.CoverSelector)
+		}
+	} else if e.Image.HasCover && !e.NoCoverFile {
+		root := rootDir(names)
+		for i, name := range names {
+			if path.Dir(filepath.ToSlash(name)) == root && IsCoverFile(name) {
+				cover = i
+				break
+			}
+		}
+	}
+
+	selected := make([]string, 0, len(names))
+	if cover != -1 {
+		selected = append(selected, names[cover])
+		names = append(names[:cover:cover], names[cover+1:]...)
+	}
+	pages, err := e.selectPages(len(names))
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range pages {
+		selected = append(selected, names[i])
+	}
+
+	// the copy goes before the first kept page following the cover, or at the end
+	if cover != -1 && e.CoverSelector != "" && !e.CoverOnly {
+		e.coverCopy = true
+		for _, i := range pages {
+			if i >= cover {
+				e.coverCopyBefore = names[i]
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// index of the cover selected by its page number (starting at 1) or by its name, -1 if not found.
+//
+// the name matches the end of the path, like page01.jpg or chapter1/page01.jpg.
+func findCover(names []string, selector string) int {
+	if page, err := strconv.Atoi(selector); err == nil {
+		if page < 1 || page > len(names) {
+			return -1
+		}
+		return page - 1
+	}
+	selector = archiveName(selector)
+	for i, name := range names {
+		name = archiveName(name)
+		if name == selector || strings.HasSuffix(name, "/"+selector) {
+			return i
+		}
+	}
+	return -1
+}
+
+// directory holding all the names, with slashes: the root of the input, or the directory wrapping all its pages.
+//
+// the cover file is only looked up there, a folder.jpg of a chapter is a page.
+func rootDir(names []string) string {
+	if len(names) == 0 {
+		return "."
+	}
+	root := path.Dir(filepath.ToSlash(names[0]))
+	for _, name := range names[1:] {
+		dir := path.Dir(filepath.ToSlash(name))
+		for root != "." && dir != root && !strings.HasPrefix(dir, strings.TrimSuffix(root, "/")+"/") {
+			root = path.Dir(root)
+		}
+	}
+	return root
+}
+
+// Check if the file is used by convention as the cover, or as the art of its folder: cover.jpg or folder.jpg.
+func IsCoverFile(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return base == "cover" || base == "folder"
+}
+
+// name of an archive entry as written in the order file
+func archiveName(name string) string {
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// extension of the input in lower case, .tar.gz is kept as one extension and the volumes .r00, .r01... are rar
+func inputExt(name string) string {
+	if strings.HasSuffix(strings.ToLower(name), ".tar.gz") {
+		return ".tar.gz"
+	}
+	if rarOldVolume.MatchString(name) {
+		return ".rar"
+	}
+	return strings.ToLower(filepath.Ext(name))
+}
+
 // Load images from input
 func (e *EPUBImageProcessor) load() (totalImages int, output chan *tasks, err error) {
 	fi, err := os.Stat(e.Input)
@@ -55,15 +469,21 @@ func (e *EPUBImageProcessor) load() (totalImages int, output chan *tasks, err er
 	if fi.IsDir() {
 		return e.loadDir()
 	} else {
-		switch ext := strings.ToLower(filepath.Ext(e.Input)); ext {
+		switch ext := inputExt(e.Input); ext {
 		case ".cbz", ".zip":
 			return e.loadCbz()
 		case ".cbr", ".rar":
 			return e.loadCbr()
+		case ".cb7", ".7z":
+			return e.load7z()
+		case ".cbt", ".tar", ".tar.gz", ".tgz":
+			return e.loadTar()
 		case ".pdf":
 			return e.loadPdf()
+		case ".djvu", ".djv":
+			return e.loadDjvu()
 		default:
-			err = fmt.Errorf("unknown file format (%s): support .cbz, .zip, .cbr, .rar, .pdf", ext)
+			err = fmt.Errorf("unknown file format (%s): support .cbz, .zip, .cbr, .rar, .cb7, .7z, .cbt, .tar, .tar.gz, .tgz, .pdf, .djvu", ext)
 			return
 		}
 	}
@@ -72,14 +492,41 @@ func (e *EPUBImageProcessor) load() (totalImages int, output chan *tasks, err er
 // load a directory of images
 func (e *EPUBImageProcessor) loadDir() (totalImages int, output chan *tasks, err error) {
 	images := make([]string, 0)
+	pageCounts := make(map[string]int)
+	var comicInfoPath string
 
 	input := filepath.Clean(e.Input)
 	err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() && e.isSupportedImage(path) {
+		if path == input {
+			return nil
+		}
+		rel, err := filepath.Rel(input, path)
+		if err != nil {
+			return err
+		}
+		if e.isExcluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if comicinfo.IsComicInfo(path) {
+			if comicInfoPath == "" {
+				comicInfoPath = path
+			}
+		} else if e.isSupportedImage(path) || e.isSniffedImage(path, func() (io.ReadCloser, error) { return os.Open(path) }) {
 			images = append(images, path)
+			if n := e.tiffPageCount(path, func() (io.ReadCloser, error) { return os.Open(path) }); n > 1 {
+				pageCounts[path] = n
+			}
+		} else {
+			e.warnUnsupported(rel)
 		}
 		return nil
 	})
@@ -88,15 +535,31 @@ func (e *EPUBImageProcessor) loadDir() (totalImages int, output chan *tasks, err
 		return
 	}
 
-	totalImages = len(images)
+	sort.Sort(sortpath.By(images, e.SortPathMode))
+	images, err = e.applyOrder(images, func(path string) string {
+		rel, _ := filepath.Rel(input, path)
+		return filepath.ToSlash(rel)
+	})
+	if err != nil {
+		return
+	}
+
+	var ci *comicinfo.ComicInfo
+	if comicInfoPath != "" {
+		ci = e.readComicInfo(comicInfoPath, func() (io.ReadCloser, error) { return os.Open(comicInfoPath) })
+	}
+	images, doublePages := e.applyComicInfo(images, ci)
+	if images, err = e.selectNames(images); err != nil {
+		return
+	}
+
+	indexedNames, totalImages := e.indexNames(images, pageCounts)
 
 	if totalImages == 0 {
 		err = errNoImagesFound
 		return
 	}
 
-	sort.Sort(sortpath.By(images, e.SortPathMode))
-
 	// Queue all file with id
 	type job struct {
 		Id   int
@@ -105,45 +568,79 @@ func (e *EPUBImageProcessor) loadDir() (totalImages int, output chan *tasks, err
 	jobs := make(chan *job)
 	go func() {
 		defer close(jobs)
-		for i, path := range images {
-			jobs <- &job{i, path}
+		for _, path := range images {
+			jobs <- &job{indexedNames[path], path}
 		}
 	}()
 
-	// read in parallel and get an image
-	output = make(chan *tasks, e.Workers)
-	wg := &sync.WaitGroup{}
-	for j := 0; j < e.WorkersRatio(50); j++ {
-		wg.Add(1)
+	// read the files in parallel, the disk access is tuned apart from the decoding.
+	// the preflight only decodes the headers, the file is then opened by the decoder.
+	type read struct {
+		*job
+		open func() (io.ReadCloser, error)
+	}
+	reads := make(chan *read, e.DecodersCount())
+	rwg := &sync.WaitGroup{}
+	for j := 0; j < e.ReadersCount(); j++ {
+		rwg.Add(1)
 		go func() {
-			defer wg.Done()
+			defer rwg.Done()
 			for job := range jobs {
-				var img image.Image
-				if !e.Dry {
-					f, err := os.Open(job.Path)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", job.Path, err)
-						os.Exit(1)
+				path := job.Path
+				r := &read{job: job, open: func() (io.ReadCloser, error) { return os.Open(path) }}
+				if !e.Dry && !e.Preflight {
+					content, err := os.ReadFile(path)
+					r.open = func() (io.ReadCloser, error) {
+						if err != nil {
+							return nil, err
+						}
+						return io.NopCloser(bytes.NewReader(content)), nil
 					}
-					img, _, err = image.Decode(f)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", job.Path, err)
-						os.Exit(1)
-					}
-					f.Close()
 				}
+				reads <- r
+			}
+		}()
+	}
+	go func() {
+		rwg.Wait()
+		close(reads)
+	}()
 
+	// decode in parallel and get an image
+	output = make(chan *tasks, e.Workers)
+	wg := &sync.WaitGroup{}
+	for j := 0; j < e.DecodersCount(); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range reads {
 				p, fn := filepath.Split(job.Path)
 				if p == input {
 					p = ""
 				} else {
 					p = p[len(input)+1:]
 				}
+
+				if n, ok := pageCounts[job.Path]; ok {
+					e.sendTiffPages(output, job.Id, p, fn, n, job.open)
+					continue
+				}
+
+				var img image.Image
+				var data []byte
+				var err error
+				if !e.Dry {
+					img, data, err = e.decode(job.open)
+				}
+
 				output <- &tasks{
-					Id:    job.Id,
-					Image: img,
-					Path:  p,
-					Name:  fn,
+					Id:         job.Id,
+					Image:      img,
+					Jpeg:       data,
+					Path:       p,
+					Name:       fn,
+					DoublePage: doublePages[job.Path],
+					Error:      err,
 				}
 			}
 		}()
@@ -160,19 +657,48 @@ func (e *EPUBImageProcessor) loadDir() (totalImages int, output chan *tasks, err
 
 // load a zip file that include images
 func (e *EPUBImageProcessor) loadCbz() (totalImages int, output chan *tasks, err error) {
-	r, err := zip.OpenReader(e.Input)
+	files, r, err := openZip(e.Input, e.Password)
 	if err != nil {
 		return
 	}
 
-	images := make([]*zip.File, 0)
-	for _, f := range r.File {
-		if !f.FileInfo().IsDir() && e.isSupportedImage(f.Name) {
+	images := make([]*zipEntry, 0)
+	pageCounts := make(map[string]int)
+	var ci *comicinfo.ComicInfo
+	for _, f := range files {
+		if f.IsDir || e.isExcluded(f.Name) {
+			continue
+		}
+		if comicinfo.IsComicInfo(f.Name) {
+			if ci == nil {
+				ci = e.readComicInfo(f.Name, f.Open)
+			}
+		} else if e.isSupportedImage(f.Name) || e.isSniffedImage(f.Name, f.Open) {
 			images = append(images, f)
+			if n := e.tiffPageCount(f.Name, f.Open); n > 1 {
+				pageCounts[f.Name] = n
+			}
+		} else {
+			e.warnUnsupported(f.Name)
 		}
 	}
 
-	totalImages = len(images)
+	names := []string{}
+	for _, img := range images {
+		names = append(names, img.Name)
+	}
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+	if names, err = e.applyOrder(names, archiveName); err != nil {
+		r.Close()
+		return
+	}
+	names, doublePages := e.applyComicInfo(names, ci)
+	if names, err = e.selectNames(names); err != nil {
+		r.Close()
+		return
+	}
+
+	indexedNames, totalImages := e.indexNames(names, pageCounts)
 
 	if totalImages == 0 {
 		r.Close()
@@ -180,26 +706,125 @@ func (e *EPUBImageProcessor) loadCbz() (totalImages int, output chan *tasks, err
 		return
 	}
 
+	type job struct {
+		Id int
+		F  *zipEntry
+	}
+	jobs := make(chan *job)
+	go func() {
+		defer close(jobs)
+		for _, img := range images {
+			if i, ok := indexedNames[img.Name]; ok {
+				jobs <- &job{i, img}
+			}
+		}
+	}()
+
+	output = make(chan *tasks, e.Workers)
+	wg := &sync.WaitGroup{}
+	for j := 0; j < e.WorkersRatio(50); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				p, fn := filepath.Split(filepath.Clean(job.F.Name))
+				if n, ok := pageCounts[job.F.Name]; ok {
+					e.sendTiffPages(output, job.Id, p, fn, n, job.F.Open)
+					continue
+				}
+
+				var img image.Image
+				var data []byte
+				var err error
+				if !e.Dry {
+					img, data, err = e.decode(job.F.Open)
+				}
+
+				output <- &tasks{
+					Id:         job.Id,
+					Image:      img,
+					Jpeg:       data,
+					Path:       p,
+					Name:       fn,
+					DoublePage: doublePages[job.F.Name],
+					Error:      err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(output)
+		r.Close()
+	}()
+	return
+}
+
+// load a 7z file that include images
+//
+// the entries of a solid archive are decoded from the start of their block.
+func (e *EPUBImageProcessor) load7z() (totalImages int, output chan *tasks, err error) {
+	r, err := sevenzip.OpenReader(e.Input)
+	if err != nil {
+		return
+	}
+
+	images := make([]*sevenzip.File, 0)
+	pageCounts := make(map[string]int)
+	var ci *comicinfo.ComicInfo
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || e.isExcluded(f.Name) {
+			continue
+		}
+		if comicinfo.IsComicInfo(f.Name) {
+			if ci == nil {
+				ci = e.readComicInfo(f.Name, f.Open)
+			}
+		} else if e.isSupportedImage(f.Name) || e.isSniffedImage(f.Name, f.Open) {
+			images = append(images, f)
+			if n := e.tiffPageCount(f.Name, f.Open); n > 1 {
+				pageCounts[f.Name] = n
+			}
+		} else {
+			e.warnUnsupported(f.Name)
+		}
+	}
+
 	names := []string{}
 	for _, img := range images {
 		names = append(names, img.Name)
 	}
 	sort.Sort(sortpath.By(names, e.SortPathMode))
+	if names, err = e.applyOrder(names, archiveName); err != nil {
+		r.Close()
+		return
+	}
+	names, doublePages := e.applyComicInfo(names, ci)
+	if names, err = e.selectNames(names); err != nil {
+		r.Close()
+		return
+	}
 
-	indexedNames := make(map[string]int)
-	for i, name := range names {
-		indexedNames[name] = i
+	indexedNames, totalImages := e.indexNames(names, pageCounts)
+
+	if totalImages == 0 {
+		r.Close()
+		err = errNoImagesFound
+		return
 	}
 
 	type job struct {
 		Id int
-		F  *zip.File
+		F  *sevenzip.File
 	}
 	jobs := make(chan *job)
 	go func() {
 		defer close(jobs)
 		for _, img := range images {
-			jobs <- &job{indexedNames[img.Name], img}
+			if i, ok := indexedNames[img.Name]; ok {
+				jobs <- &job{i, img}
+			}
 		}
 	}()
 
@@ -210,27 +835,27 @@ func (e *EPUBImageProcessor) loadCbz() (totalImages int, output chan *tasks, err
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
+				p, fn := filepath.Split(filepath.Clean(job.F.Name))
+				if n, ok := pageCounts[job.F.Name]; ok {
+					e.sendTiffPages(output, job.Id, p, fn, n, job.F.Open)
+					continue
+				}
+
 				var img image.Image
+				var data []byte
+				var err error
 				if !e.Dry {
-					f, err := job.F.Open()
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", job.F.Name, err)
-						os.Exit(1)
-					}
-					img, _, err = image.Decode(f)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", job.F.Name, err)
-						os.Exit(1)
-					}
-					f.Close()
+					img, data, err = e.decode(job.F.Open)
 				}
 
-				p, fn := filepath.Split(filepath.Clean(job.F.Name))
 				output <- &tasks{
-					Id:    job.Id,
-					Image: img,
-					Path:  p,
-					Name:  fn,
+					Id:         job.Id,
+					Image:      img,
+					Jpeg:       data,
+					Path:       p,
+					Name:       fn,
+					DoublePage: doublePages[job.F.Name],
+					Error:      err,
 				}
 			}
 		}()
@@ -245,34 +870,58 @@ func (e *EPUBImageProcessor) loadCbz() (totalImages int, output chan *tasks, err
 }
 
 // load a rar file that include images
+//
+// a rar split in volumes is read from its first volume, the reader follows the next ones.
 func (e *EPUBImageProcessor) loadCbr() (totalImages int, output chan *tasks, err error) {
 	var isSolid bool
-	files, err := rardecode.List(e.Input)
+	input := rarFirstVolume(e.Input)
+	if err = checkRarPassword(input, e.Password); err != nil {
+		err = rarError(input, err)
+		return
+	}
+	files, err := rardecode.List(input, rarOptions(e.Password)...)
 	if err != nil {
+		err = rarError(input, err)
 		return
 	}
 
 	names := make([]string, 0)
+	pageCounts := make(map[string]int)
+	var ci *comicinfo.ComicInfo
 	for _, f := range files {
-		if !f.IsDir && e.isSupportedImage(f.Name) {
+		if f.IsDir || e.isExcluded(f.Name) {
+			continue
+		}
+		if comicinfo.IsComicInfo(f.Name) {
+			if ci == nil {
+				ci = e.readComicInfo(f.Name, f.Open)
+			}
+		} else if e.isSupportedImage(f.Name) || e.isSniffedImage(f.Name, f.Open) {
 			if f.Solid {
 				isSolid = true
 			}
 			names = append(names, f.Name)
+			if n := e.tiffPageCount(f.Name, f.Open); n > 1 {
+				pageCounts[f.Name] = n
+			}
+		} else {
+			e.warnUnsupported(f.Name)
 		}
 	}
 
-	totalImages = len(names)
-	if totalImages == 0 {
-		err = errNoImagesFound
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+	if names, err = e.applyOrder(names, archiveName); err != nil {
+		return
+	}
+	names, doublePages := e.applyComicInfo(names, ci)
+	if names, err = e.selectNames(names); err != nil {
 		return
 	}
 
-	sort.Sort(sortpath.By(names, e.SortPathMode))
-
-	indexedNames := make(map[string]int)
-	for i, name := range names {
-		indexedNames[name] = i
+	indexedNames, totalImages := e.indexNames(names, pageCounts)
+	if totalImages == 0 {
+		err = errNoImagesFound
+		return
 	}
 
 	type job struct {
@@ -281,14 +930,22 @@ func (e *EPUBImageProcessor) loadCbr() (totalImages int, output chan *tasks, err
 		Open func() (io.ReadCloser, error)
 	}
 
+	// some archives mis-report the solidity
+	switch e.RarMode {
+	case "solid":
+		isSolid = true
+	case "random":
+		isSolid = false
+	}
+
 	jobs := make(chan *job)
 	go func() {
 		defer close(jobs)
 		if isSolid && !e.Dry {
-			r, rerr := rardecode.OpenReader(e.Input)
+			r, rerr := rardecode.OpenReader(input, rarOptions(e.Password)...)
 			if rerr != nil {
-				fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", e.Input, rerr)
-				os.Exit(1)
+				jobs <- &job{-1, e.Input, failedOpen(rarError(input, rerr))}
+				return
 			}
 			defer r.Close()
 			for {
@@ -297,15 +954,15 @@ func (e *EPUBImageProcessor) loadCbr() (totalImages int, output chan *tasks, err
 					if rerr == io.EOF {
 						break
 					}
-					fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", f.Name, rerr)
-					os.Exit(1)
+					jobs <- &job{-1, e.Input, failedOpen(rarError(input, rerr))}
+					return
 				}
 				if i, ok := indexedNames[f.Name]; ok {
 					var b bytes.Buffer
 					_, rerr = io.Copy(&b, r)
 					if rerr != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", f.Name, rerr)
-						os.Exit(1)
+						jobs <- &job{-1, f.Name, failedOpen(rarError(input, rerr))}
+						return
 					}
 					jobs <- &job{i, f.Name, func() (io.ReadCloser, error) {
 						return io.NopCloser(bytes.NewReader(b.Bytes())), nil
@@ -329,27 +986,190 @@ func (e *EPUBImageProcessor) loadCbr() (totalImages int, output chan *tasks, err
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
+				p, fn := filepath.Split(filepath.Clean(job.Name))
+				if n, ok := pageCounts[job.Name]; ok {
+					e.sendTiffPages(output, job.Id, p, fn, n, job.Open)
+					continue
+				}
+
 				var img image.Image
+				var data []byte
+				var err error
 				if !e.Dry {
-					f, err := job.Open()
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", job.Name, err)
-						os.Exit(1)
-					}
-					img, _, err = image.Decode(f)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nerror processing image %s: %s\n", job.Name, err)
-						os.Exit(1)
-					}
-					f.Close()
+					img, data, err = e.decode(job.Open)
 				}
 
+				output <- &tasks{
+					Id:         job.Id,
+					Image:      img,
+					Jpeg:       data,
+					Path:       p,
+					Name:       fn,
+					DoublePage: doublePages[job.Name],
+					Error:      err,
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(output)
+	}()
+	return
+}
+
+// open function of an entry that couldn't be read from a sequential archive, the error is reported by the worker.
+//
+// the next entries are lost, so the job takes the id -1 to fail the conversion even when skipping the bad images.
+func failedOpen(err error) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) { return nil, err }
+}
+
+// read a tar file, compressed with gzip if the extension is .tar.gz or .tgz
+func (e *EPUBImageProcessor) openTar() (*tar.Reader, io.Closer, error) {
+	f, err := os.Open(e.Input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ext := inputExt(e.Input); ext != ".tar.gz" && ext != ".tgz" {
+		return tar.NewReader(f), f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return tar.NewReader(gz), f, nil
+}
+
+// load a tar file that include images
+//
+// the tar is sequential, it is listed a first time, then read again to send the images to the workers.
+func (e *EPUBImageProcessor) loadTar() (totalImages int, output chan *tasks, err error) {
+	tr, f, err := e.openTar()
+	if err != nil {
+		return
+	}
+
+	names := make([]string, 0)
+	pageCounts := make(map[string]int)
+	var ci *comicinfo.ComicInfo
+	for {
+		h, terr := tr.Next()
+		if terr == io.EOF {
+			break
+		}
+		if terr != nil {
+			f.Close()
+			err = terr
+			return
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if h.Typeflag != tar.TypeReg || e.isExcluded(h.Name) {
+			continue
+		}
+		if comicinfo.IsComicInfo(h.Name) {
+			if ci == nil {
+				ci = e.readComicInfo(h.Name, open)
+			}
+		} else if e.isSupportedImage(h.Name) || e.isSniffedImage(h.Name, open) {
+			names = append(names, h.Name)
+			if n := e.tiffPageCount(h.Name, open); n > 1 {
+				pageCounts[h.Name] = n
+			}
+		} else {
+			e.warnUnsupported(h.Name)
+		}
+	}
+	f.Close()
+
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+	if names, err = e.applyOrder(names, archiveName); err != nil {
+		return
+	}
+	names, doublePages := e.applyComicInfo(names, ci)
+	if names, err = e.selectNames(names); err != nil {
+		return
+	}
+
+	indexedNames, totalImages := e.indexNames(names, pageCounts)
+	if totalImages == 0 {
+		err = errNoImagesFound
+		return
+	}
+
+	type job struct {
+		Id   int
+		Name string
+		Open func() (io.ReadCloser, error)
+	}
+
+	jobs := make(chan *job)
+	go func() {
+		defer close(jobs)
+		if e.Dry {
+			for name, i := range indexedNames {
+				jobs <- &job{i, name, nil}
+			}
+			return
+		}
+		tr, f, terr := e.openTar()
+		if terr != nil {
+			jobs <- &job{-1, e.Input, failedOpen(terr)}
+			return
+		}
+		defer f.Close()
+		for {
+			h, terr := tr.Next()
+			if terr == io.EOF {
+				break
+			}
+			if terr != nil {
+				jobs <- &job{-1, e.Input, failedOpen(terr)}
+				return
+			}
+			if i, ok := indexedNames[h.Name]; ok && h.Typeflag == tar.TypeReg {
+				var b bytes.Buffer
+				if _, terr = io.Copy(&b, tr); terr != nil {
+					jobs <- &job{-1, h.Name, failedOpen(terr)}
+					return
+				}
+				jobs <- &job{i, h.Name, func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(b.Bytes())), nil
+				}}
+			}
+		}
+	}()
+
+	// send file to the queue
+	output = make(chan *tasks, e.Workers)
+	wg := &sync.WaitGroup{}
+	for j := 0; j < e.WorkersRatio(50); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
 				p, fn := filepath.Split(filepath.Clean(job.Name))
+				if n, ok := pageCounts[job.Name]; ok {
+					e.sendTiffPages(output, job.Id, p, fn, n, job.Open)
+					continue
+				}
+
+				var img image.Image
+				var data []byte
+				var err error
+				if !e.Dry {
+					img, data, err = e.decode(job.Open)
+				}
+
 				output <- &tasks{
-					Id:    job.Id,
-					Image: img,
-					Path:  p,
-					Name:  fn,
+					Id:         job.Id,
+					Image:      img,
+					Jpeg:       data,
+					Path:       p,
+					Name:       fn,
+					DoublePage: doublePages[job.Name],
+					Error:      err,
 				}
 			}
 		}()
@@ -369,30 +1189,254 @@ func (e *EPUBImageProcessor) loadPdf() (totalImages int, output chan *tasks, err
 		return
 	}
 
-	totalImages = len(pdf.Pages())
-	pageFmt := fmt.Sprintf("page %%0%dd", len(fmt.Sprintf("%d", totalImages)))
+	pages := pdf.Pages()
+	selected, err := e.selectPages(len(pages))
+	if err != nil {
+		pdf.Close()
+		return
+	}
+	totalImages = len(selected)
+
+	// the renderer is only needed for the pages without image
+	pdftoppm, lookErr := exec.LookPath("pdftoppm")
+	if e.PdfRender == "always" && lookErr != nil && !e.Dry {
+		pdf.Close()
+		err = fmt.Errorf("pdftoppm not found, install poppler to render the pdf: %w", lookErr)
+		return
+	}
+
+	var tmpDir string
+	if !e.Dry && e.PdfRender != "never" && lookErr == nil {
+		if tmpDir, err = os.MkdirTemp("", "go-comic-converter-pdf-"); err != nil {
+			pdf.Close()
+			return
+		}
+	}
+
+	type job struct {
+		Id   int
+		Page int
+	}
+	jobs := make(chan *job)
+	go func() {
+		defer close(jobs)
+		for id, i := range selected {
+			jobs <- &job{id, i}
+		}
+	}()
+
+	pageFmt := fmt.Sprintf("page %%0%dd", len(fmt.Sprintf("%d", len(pages))))
+	output = make(chan *tasks, e.Workers)
+	wg := &sync.WaitGroup{}
+	for j := 0; j < e.WorkersRatio(50); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// the reader caches the objects without lock, each worker opens its own
+			var r *pdfread.PdfReaderT
+			defer func() {
+				if r != nil {
+					r.Close()
+				}
+			}()
+
+			for job := range jobs {
+				id, i := job.Id, job.Page
+				var img image.Image
+				var err error
+				if !e.Dry {
+					if e.PdfRender == "always" {
+						img, err = e.renderPdfPage(pdftoppm, tmpDir, i+1)
+					} else {
+						if r == nil {
+							r = pdfread.Load(e.Input)
+						}
+						if img, err = e.extractPdfPage(r, i+1); err != nil && e.PdfRender == "auto" {
+							// vector or text page
+							if tmpDir != "" {
+								img, err = e.renderPdfPage(pdftoppm, tmpDir, i+1)
+							} else {
+								err = fmt.Errorf("%w: install poppler to render the pages without image", err)
+							}
+						}
+					}
+				}
+
+				output <- &tasks{
+					Id:    id,
+					Image: img,
+					Path:  "",
+					Name:  fmt.Sprintf(pageFmt, i+1),
+					Error: err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(output)
+		pdf.Close()
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	return
+}
+
+// extract the image of a page, reduced to the requested dpi.
+func (e *EPUBImageProcessor) extractPdfPage(pdf *pdfread.PdfReaderT, page int) (image.Image, error) {
+	if pdf == nil {
+		return nil, fmt.Errorf("can't read pdf")
+	}
+	img, err := pdfimage.Extract(pdf, page)
+	if err != nil {
+		return nil, err
+	}
+	return e.pdfResize(img, pdf.Arr(pdf.Att("/MediaBox", pdf.Pages()[page-1]))), nil
+}
+
+// reduce the image extracted from a pdf page to the requested dpi.
+//
+// the size of the page is given by the media box in points (1/72 inch).
+func (e *EPUBImageProcessor) pdfResize(img image.Image, mediaBox [][]byte) image.Image {
+	if e.PdfDpi == 0 || len(mediaBox) != 4 {
+		return img
+	}
+
+	box := make([]float64, 4)
+	for i, v := range mediaBox {
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return img
+		}
+		box[i] = f
+	}
+
+	width := int(math.Abs(box[2]-box[0]) / 72 * float64(e.PdfDpi))
+	height := int(math.Abs(box[3]-box[1]) / 72 * float64(e.PdfDpi))
+	if width == 0 || height == 0 || (img.Bounds().Dx() <= width && img.Bounds().Dy() <= height) {
+		return img
+	}
+
+	g := gift.New(gift.ResizeToFit(width, height, gift.LanczosResampling))
+	dst := image.NewNRGBA(g.Bounds(img.Bounds()))
+	g.Draw(dst, img)
+	return dst
+}
+
+// render a page of the pdf into a png with pdftoppm and decode it, at the pdf dpi or 300 dpi
+func (e *EPUBImageProcessor) renderPdfPage(pdftoppm string, tmpDir string, page int) (image.Image, error) {
+	dpi := e.PdfDpi
+	if dpi == 0 {
+		dpi = 300
+	}
+
+	prefix := filepath.Join(tmpDir, fmt.Sprintf("page_%d", page))
+	filename := prefix + ".png"
+	defer os.Remove(filename)
+
+	p := strconv.Itoa(page)
+	if out, err := exec.Command(pdftoppm, "-f", p, "-l", p, "-r", strconv.Itoa(dpi), "-png", "-singlefile", e.Input, prefix).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// extract image from a djvu
+//
+// the rendering is done by the djvulibre tools (djvused and ddjvu) that need to be installed.
+func (e *EPUBImageProcessor) loadDjvu() (totalImages int, output chan *tasks, err error) {
+	ddjvu, err := exec.LookPath("ddjvu")
+	if err != nil {
+		err = fmt.Errorf("ddjvu not found, install djvulibre to read djvu: %w", err)
+		return
+	}
+	djvused, err := exec.LookPath("djvused")
+	if err != nil {
+		err = fmt.Errorf("djvused not found, install djvulibre to read djvu: %w", err)
+		return
+	}
+
+	pages, err := exec.Command(djvused, "-e", "n", e.Input).Output()
+	if err != nil {
+		err = fmt.Errorf("can't read djvu: %w", err)
+		return
+	}
+	nbPages, err := strconv.Atoi(strings.TrimSpace(string(pages)))
+	if err != nil {
+		err = fmt.Errorf("can't read djvu: %w", err)
+		return
+	}
+	if nbPages == 0 {
+		err = errNoImagesFound
+		return
+	}
+	selected, err := e.selectPages(nbPages)
+	if err != nil {
+		return
+	}
+	totalImages = len(selected)
+
+	pageFmt := fmt.Sprintf("page %%0%dd", len(fmt.Sprintf("%d", nbPages)))
 	output = make(chan *tasks)
 	go func() {
 		defer close(output)
-		defer pdf.Close()
-		for i := 0; i < totalImages; i++ {
+
+		var tmpDir string
+		if !e.Dry {
+			dir, err := os.MkdirTemp("", "go-comic-converter-djvu-")
+			if err != nil {
+				output <- &tasks{Id: -1, Name: e.Input, Error: err}
+				return
+			}
+			defer os.RemoveAll(dir)
+			tmpDir = dir
+		}
+
+		for id, i := range selected {
 			var img image.Image
+			var err error
 			if !e.Dry {
-				img, err = pdfimage.Extract(pdf, i+1)
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					os.Exit(1)
-				}
+				img, err = extractDjvuPage(ddjvu, e.Input, tmpDir, i+1)
 			}
 
 			output <- &tasks{
-				Id:    i,
+				Id:    id,
 				Image: img,
 				Path:  "",
 				Name:  fmt.Sprintf(pageFmt, i+1),
+				Error: err,
 			}
 		}
 	}()
 
 	return
 }
+
+// render a page of the djvu into a tiff and decode it
+func extractDjvuPage(ddjvu string, input string, tmpDir string, page int) (image.Image, error) {
+	filename := filepath.Join(tmpDir, fmt.Sprintf("page_%d.tiff", page))
+	defer os.Remove(filename)
+
+	if out, err := exec.Command(ddjvu, "-format=tiff", fmt.Sprintf("-page=%d", page), input, filename).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ddjvu: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return tiff.Decode(f)
+}
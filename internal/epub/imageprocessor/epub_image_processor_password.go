@@ -0,0 +1,157 @@
+package epubimageprocessor
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/nwaples/rardecode/v2"
+	cryptozip "github.com/yeka/zip"
+)
+
+// entry of a zip input
+type zipEntry struct {
+	Name  string
+	IsDir bool
+	Open  func() (io.ReadCloser, error)
+}
+
+// list the entries of a zip, decrypted with the password if the zip is encrypted.
+//
+// the standard reader is used unless an entry is encrypted, it doesn't support the encryption.
+// the password is checked on the first encrypted entry before the conversion.
+func openZip(input string, password string) ([]*zipEntry, io.Closer, error) {
+	r, err := zip.OpenReader(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encrypted := false
+	entries := make([]*zipEntry, 0, len(r.File))
+	for _, f := range r.File {
+		// bit 0 of the flags: the entry is encrypted
+		encrypted = encrypted || f.Flags&0x1 != 0
+		entries = append(entries, &zipEntry{f.Name, f.FileInfo().IsDir(), f.Open})
+	}
+	if !encrypted {
+		return entries, r, nil
+	}
+	r.Close()
+
+	if password == "" {
+		return nil, nil, fmt.Errorf("%s is encrypted, set the password with -password", input)
+	}
+
+	cr, err := cryptozip.OpenReader(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	var first *cryptozip.File
+	entries = make([]*zipEntry, 0, len(cr.File))
+	for _, f := range cr.File {
+		if f.IsEncrypted() {
+			f.SetPassword(password)
+			if first == nil && !f.FileInfo().IsDir() {
+				first = f
+			}
+		}
+		entries = append(entries, &zipEntry{f.Name, f.FileInfo().IsDir(), f.Open})
+	}
+	if first != nil {
+		if err := checkPassword(input, first.Open); err != nil {
+			cr.Close()
+			return nil, nil, err
+		}
+	}
+	return entries, cr, nil
+}
+
+// options of the rar reader, with the password if set
+func rarOptions(password string) []rardecode.Option {
+	if password == "" {
+		return nil
+	}
+	return []rardecode.Option{rardecode.Password(password)}
+}
+
+// check the password on the first file of a rar, before the conversion.
+func checkRarPassword(input string, password string) error {
+	if password == "" {
+		return nil
+	}
+	return checkPassword(input, func() (io.ReadCloser, error) {
+		r, err := rardecode.OpenReader(input, rarOptions(password)...)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			f, err := r.Next()
+			if err == io.EOF {
+				return r, nil
+			}
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+			if !f.IsDir {
+				return r, nil
+			}
+		}
+	})
+}
+
+// read an encrypted entry to the end.
+//
+// the zipcrypto and rar 3 decryptions don't verify the password, a wrong one is only detected by the checksum
+// at the end of the entry, or by a decompression error.
+func checkPassword(input string, open func() (io.ReadCloser, error)) error {
+	if err := verifyEntry(open); err != nil {
+		if errors.Is(err, cryptozip.ErrPassword) {
+			return fmt.Errorf("wrong password for %s", input)
+		}
+		return fmt.Errorf("wrong password for %s: %w", input, err)
+	}
+	return nil
+}
+
+// Read a file of a zip or a rar input by its name, case insensitive, like the loaders: with the password,
+// and a rar split in volumes from its first volume. The name of the entry is empty if it is not found.
+func ReadArchiveFile(input string, password string, name string) (data []byte, entry string, err error) {
+	var names []string
+	var opens []func() (io.ReadCloser, error)
+	switch strings.ToLower(filepath.Ext(input)) {
+	case ".cbz", ".zip":
+		files, r, err := openZip(input, password)
+		if err != nil {
+			return nil, "", err
+		}
+		defer r.Close()
+		for _, f := range files {
+			names, opens = append(names, f.Name), append(opens, f.Open)
+		}
+	case ".cbr", ".rar":
+		files, err := rardecode.List(rarFirstVolume(input), rarOptions(password)...)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, f := range files {
+			names, opens = append(names, f.Name), append(opens, f.Open)
+		}
+	}
+
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			f, err := opens[i]()
+			if err != nil {
+				return nil, n, err
+			}
+			defer f.Close()
+			data, err = io.ReadAll(f)
+			return data, n, err
+		}
+	}
+	return nil, "", nil
+}
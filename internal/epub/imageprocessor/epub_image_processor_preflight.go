@@ -0,0 +1,79 @@
+package epubimageprocessor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+)
+
+// image with only the header decoded
+type imageConfig struct {
+	config image.Config
+}
+
+func (i *imageConfig) ColorModel() color.Model {
+	return i.config.ColorModel
+}
+
+func (i *imageConfig) Bounds() image.Rectangle {
+	return image.Rect(0, 0, i.config.Width, i.config.Height)
+}
+
+func (i *imageConfig) At(x, y int) color.Color {
+	return color.White
+}
+
+type PreflightReport struct {
+	Pages         int
+	Dimensions    map[string]int
+	Unreadable    map[string]string
+	EstimatedSize uint64
+}
+
+// check the health of the input without converting it.
+//
+// only the header of the images are decoded, except for pdf and djvu that need a full rendering.
+func (e *EPUBImageProcessor) Inspect() (*PreflightReport, error) {
+	_, imageInput, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PreflightReport{
+		Dimensions: map[string]int{},
+		Unreadable: map[string]string{},
+	}
+	for input := range imageInput {
+		r.Pages++
+		if input.Error != nil {
+			r.Unreadable[filepath.Join(input.Path, input.Name)] = input.Error.Error()
+			continue
+		}
+		b := input.Image.Bounds()
+		r.Dimensions[fmt.Sprintf("%dx%d", b.Dx(), b.Dy())]++
+		r.EstimatedSize += e.estimateSize(b)
+	}
+
+	return r, nil
+}
+
+// rough estimation of the size of a converted page.
+func (e *EPUBImageProcessor) estimateSize(b image.Rectangle) uint64 {
+	w, h := float64(b.Dx()), float64(b.Dy())
+	if ratio := math.Max(w/float64(e.Image.View.Width), h/float64(e.Image.View.Height)); e.Image.Resize && ratio > 1 {
+		w, h = w/ratio, h/ratio
+	}
+
+	// observed average for comics pages
+	bytesPerPixel := 0.25
+	if e.Image.Format == "png" {
+		bytesPerPixel = 0.75
+	}
+	if !e.Image.GrayScale {
+		bytesPerPixel *= 2
+	}
+
+	return uint64(w * h * bytesPerPixel)
+}
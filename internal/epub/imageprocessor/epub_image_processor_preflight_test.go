@@ -0,0 +1,92 @@
+package epubimageprocessor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+// directory of jpeg pages
+func jpegDir(tb testing.TB, pages int, size image.Point) string {
+	var page bytes.Buffer
+	if err := jpeg.Encode(&page, image.NewGray(image.Rectangle{Max: size}), nil); err != nil {
+		tb.Fatal(err)
+	}
+	dir := tb.TempDir()
+	for i := 0; i < pages; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%03d.jpg", i)), page.Bytes(), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInspectDirHeaders(t *testing.T) {
+	dir := jpegDir(t, 2, image.Pt(60, 80))
+
+	// only the header of the png is valid, the pixels are cut
+	var page bytes.Buffer
+	if err := png.Encode(&page, image.NewGray(image.Rect(0, 0, 100, 50))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "002.png"), page.Bytes()[:33], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a big scan, the pixels are noise so the file stays big
+	noise := image.NewGray(image.Rect(0, 0, 2000, 3000))
+	rand.New(rand.NewSource(1)).Read(noise.Pix)
+	page.Reset()
+	if err := jpeg.Encode(&page, noise, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "003.jpg"), page.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(&epuboptions.Options{Input: dir, Preflight: true, Workers: 2, Image: &epuboptions.Image{View: &epuboptions.View{Width: 1072, Height: 1448}}})
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	r, err := e.Inspect()
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pages != 4 || len(r.Unreadable) != 0 || r.Dimensions["60x80"] != 2 || r.Dimensions["100x50"] != 1 || r.Dimensions["2000x3000"] != 1 {
+		t.Errorf("report = %+v", r)
+	}
+	// only the headers are read, not the files
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > uint64(page.Len()/2) {
+		t.Errorf("%d bytes allocated to inspect a scan of %d bytes", alloc, page.Len())
+	}
+}
+
+// the disk is tuned apart from the decoding: few readers for a spinning disk, more for a ssd.
+func BenchmarkLoadDir(b *testing.B) {
+	dir := jpegDir(b, 32, image.Pt(1200, 1800))
+	for _, c := range []struct{ readers, decoders int }{{1, 1}, {1, 4}, {4, 4}, {8, 4}} {
+		b.Run(fmt.Sprintf("readers=%d/decoders=%d", c.readers, c.decoders), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				e := New(&epuboptions.Options{Input: dir, Workers: 4, Readers: c.readers, Decoders: c.decoders, Image: &epuboptions.Image{}})
+				_, output, err := e.loadDir()
+				if err != nil {
+					b.Fatal(err)
+				}
+				for t := range output {
+					if t.Error != nil {
+						b.Fatal(t.Error)
+					}
+				}
+			}
+		})
+	}
+}
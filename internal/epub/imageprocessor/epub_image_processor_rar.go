@@ -0,0 +1,48 @@
+package epubimageprocessor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// name.part1.rar, name.part2.rar...
+	rarPartVolume = regexp.MustCompile(`(?i)^(.*\.part)(\d+)(\.(?:rar|cbr))$`)
+	// name.rar, name.r00, name.r01... or name.cbr, name.c00...
+	rarOldVolume = regexp.MustCompile(`(?i)^(.*)\.([rc])\d\d$`)
+)
+
+// first volume of a rar split in volumes, the input itself otherwise.
+//
+// the reader follows the next volumes from the first one, so any volume can be given as input.
+func rarFirstVolume(input string) string {
+	if m := rarPartVolume.FindStringSubmatch(input); m != nil {
+		if n, _ := strconv.Atoi(m[2]); n > 1 {
+			return fmt.Sprintf("%s%0*d%s", m[1], len(m[2]), 1, m[3])
+		}
+	} else if m := rarOldVolume.FindStringSubmatch(input); m != nil {
+		if m[2] == "c" || m[2] == "C" {
+			return m[1] + ".cbr"
+		}
+		return m[1] + ".rar"
+	}
+	return input
+}
+
+// Check if the file is a volume of a rar after the first one, it is converted with the first volume.
+func IsRarNextVolume(path string) bool {
+	return rarFirstVolume(path) != path
+}
+
+// report a missing volume with its name, the reader only fails to open the next file
+func rarError(input string, err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) && errors.Is(err, fs.ErrNotExist) && filepath.Clean(pe.Path) != filepath.Clean(input) {
+		return fmt.Errorf("missing volume %s of %s", filepath.Base(pe.Path), input)
+	}
+	return err
+}
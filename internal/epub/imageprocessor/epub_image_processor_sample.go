@@ -0,0 +1,69 @@
+package epubimageprocessor
+
+import (
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+)
+
+// ids of the pages inspected by a heuristic, evenly spaced among the first window pages after the cover.
+//
+// the number of pages is n, or the sample pages if set. The pages are read before the conversion starts,
+// so the window keeps the number of pages waiting in memory predictable.
+func (e *EPUBImageProcessor) sampleIds(total, n, window int) []int {
+	firstId := 0
+	if e.Image.HasCover {
+		firstId = 1
+	}
+	if e.SamplePages > 0 {
+		n = e.SamplePages
+	}
+	if window < n {
+		window = n
+	}
+	if window > total-firstId {
+		window = total - firstId
+	}
+	if window <= 0 {
+		return nil
+	}
+
+	ids := epubimage.Sample(window, n)
+	for i := range ids {
+		ids[i] += firstId
+	}
+	return ids
+}
+
+// read the input until the pages of ids are received.
+//
+// the replay outputs the pages read, followed by the rest of the input.
+func (e *EPUBImageProcessor) collect(input chan *tasks, ids []int) (sampled map[int]*tasks, replay chan *tasks) {
+	wanted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	pending := make([]*tasks, 0)
+	sampled = make(map[int]*tasks, len(ids))
+	for len(sampled) < len(wanted) {
+		t, ok := <-input
+		if !ok {
+			break
+		}
+		pending = append(pending, t)
+		if wanted[t.Id] {
+			sampled[t.Id] = t
+		}
+	}
+
+	replay = make(chan *tasks, e.Workers)
+	go func() {
+		defer close(replay)
+		for _, t := range pending {
+			replay <- t
+		}
+		for t := range input {
+			replay <- t
+		}
+	}()
+	return
+}
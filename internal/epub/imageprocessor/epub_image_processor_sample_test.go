@@ -0,0 +1,53 @@
+package epubimageprocessor
+
+import (
+	"fmt"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+func TestSampleIds(t *testing.T) {
+	for _, c := range []struct {
+		hasCover    bool
+		samplePages int
+		total       int
+		n, window   int
+		want        string
+	}{
+		{false, 0, 10, 1, 0, "[0]"},
+		{true, 0, 10, 1, 0, "[1]"},
+		{true, 3, 10, 1, 0, "[1 2 3]"},
+		{true, 0, 100, 4, 16, "[1 5 9 13]"},
+		{true, 0, 5, 4, 16, "[1 2 3 4]"},
+		{true, 0, 1, 1, 0, "[]"},
+	} {
+		e := New(&epuboptions.Options{SamplePages: c.samplePages, Image: &epuboptions.Image{HasCover: c.hasCover}})
+		if got := fmt.Sprint(e.sampleIds(c.total, c.n, c.window)); got != c.want {
+			t.Errorf("sampleIds(%d, %d, %d) with cover %v and %d sample pages = %s, want %s", c.total, c.n, c.window, c.hasCover, c.samplePages, got, c.want)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	input := make(chan *tasks)
+	go func() {
+		defer close(input)
+		for _, id := range []int{2, 0, 1, 3, 4} {
+			input <- &tasks{Id: id}
+		}
+	}()
+
+	e := New(&epuboptions.Options{Workers: 1})
+	sampled, replay := e.collect(input, []int{0, 1})
+	if len(sampled) != 2 || sampled[0] == nil || sampled[1] == nil {
+		t.Fatalf("sampled = %v, want the pages 0 and 1", sampled)
+	}
+	ids := []int{}
+	for t := range replay {
+		ids = append(ids, t.Id)
+	}
+	if got := fmt.Sprint(ids); got != "[2 0 1 3 4]" {
+		t.Errorf("replay = %s, want [2 0 1 3 4]", got)
+	}
+}
@@ -0,0 +1,91 @@
+package epubimageprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+// cbz of stored jpeg pages, the data of the page bad is garbled
+func corruptCbz(t *testing.T, path string, names []string, bad string) {
+	var page bytes.Buffer
+	if err := jpeg.Encode(&page, image.NewGray(image.Rect(0, 0, 60, 80)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(page.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the entries are stored as is, so the first bytes of the bad one follow its name
+	data := buf.Bytes()
+	i := bytes.Index(data, []byte(bad)) + len(bad)
+	for j := i; j < i+64; j++ {
+		data[j] ^= 0xff
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkipBadCorruptZip(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "comic.cbz")
+	corruptCbz(t, input, []string{"page01.jpg", "page02.jpg", "page03.jpg", "page04.jpg"}, "page02.jpg")
+
+	var lastDone, lastTotal int
+	e := New(&epuboptions.Options{
+		Input:   input,
+		Output:  filepath.Join(dir, "comic.epub"),
+		Workers: 2,
+		Quiet:   true,
+		SkipBad: true,
+		Image: &epuboptions.Image{
+			Crop:    &epuboptions.Crop{},
+			Quality: 85,
+			Gamma:   1,
+			Format:  "jpeg",
+			View:    &epuboptions.View{Width: 100, Height: 100},
+		},
+		OnProgress: func(done, total int) { lastDone, lastTotal = done, total },
+	})
+	images, err := e.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(images) != 3 {
+		t.Fatalf("%d pages, want 3", len(images))
+	}
+	for i, name := range pageOrder(images) {
+		if images[i].Id != i {
+			t.Errorf("page %s has the id %d, want %d", name, images[i].Id, i)
+		}
+		if want := []string{"page01.jpg", "page03.jpg", "page04.jpg"}[i]; name != want {
+			t.Errorf("page %d = %s, want %s", i, name, want)
+		}
+	}
+	if got := e.Skipped(); got != 1 {
+		t.Errorf("skipped = %d, want 1", got)
+	}
+	if lastDone != 3 || lastTotal != 3 {
+		t.Errorf("progress = %d/%d, want 3/3", lastDone, lastTotal)
+	}
+}
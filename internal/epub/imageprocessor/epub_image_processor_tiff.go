@@ -0,0 +1,208 @@
+package epubimageprocessor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/tiff"
+)
+
+func isTiff(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tif", ".tiff":
+		return true
+	}
+	return false
+}
+
+// read a tiff at any offset without loading it, the file is read forward and reopened to go back.
+type tiffStream struct {
+	open func() (io.ReadCloser, error)
+	r    io.ReadCloser
+	pos  int64
+}
+
+func (t *tiffStream) ReadAt(p []byte, off int64) (int, error) {
+	if t.r == nil || off < t.pos {
+		t.Close()
+		r, err := t.open()
+		if err != nil {
+			return 0, err
+		}
+		t.r, t.pos = r, 0
+	}
+	if s, ok := t.r.(io.Seeker); ok {
+		if _, err := s.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+	} else if _, err := io.CopyN(io.Discard, t.r, off-t.pos); err != nil {
+		t.pos = -1
+		return 0, io.EOF
+	}
+	n, err := io.ReadFull(t.r, p)
+	t.pos = off + int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (t *tiffStream) Close() error {
+	if t.r == nil {
+		return nil
+	}
+	err := t.r.Close()
+	t.r = nil
+	return err
+}
+
+// offsets of the directories (one by page) of a tiff, following the chain from the header.
+//
+// only the header and the number of entries of each directory are read, nil if the header is not a tiff one.
+func tiffDirectories(r io.ReaderAt) (binary.ByteOrder, []uint32) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, nil
+	}
+	var bo binary.ByteOrder
+	switch string(header[0:4]) {
+	case "II\x2a\x00":
+		bo = binary.LittleEndian
+	case "MM\x00\x2a":
+		bo = binary.BigEndian
+	default:
+		return nil, nil
+	}
+
+	offsets := []uint32{}
+	seen := map[uint32]bool{}
+	b := make([]byte, 4)
+	off := bo.Uint32(header[4:8])
+	for off != 0 && !seen[off] {
+		if _, err := r.ReadAt(b[:2], int64(off)); err != nil {
+			break
+		}
+		seen[off] = true
+		offsets = append(offsets, off)
+		if _, err := r.ReadAt(b, int64(off)+2+int64(bo.Uint16(b[:2]))*12); err != nil {
+			break
+		}
+		off = bo.Uint32(b)
+	}
+	return bo, offsets
+}
+
+// number of pages of a tiff, 1 for the other images.
+//
+// the header tells if the image is a tiff, it is read for the tiff names and the files without extension found by -sniff.
+func (e *EPUBImageProcessor) tiffPageCount(name string, open func() (io.ReadCloser, error)) int {
+	if !isTiff(name) && filepath.Ext(name) != "" {
+		return 1
+	}
+	r := &tiffStream{open: open}
+	defer r.Close()
+	if _, offsets := tiffDirectories(r); len(offsets) > 1 {
+		return len(offsets)
+	}
+	return 1
+}
+
+// first id of each image, a multi-page tiff takes an id for each of its pages.
+//
+// the id of the page before which the copy of the cover goes is resolved at the same time.
+func (e *EPUBImageProcessor) indexNames(names []string, pageCounts map[string]int) (indexedNames map[string]int, totalImages int) {
+	indexedNames = make(map[string]int)
+	for _, name := range names {
+		indexedNames[name] = totalImages
+		if n, ok := pageCounts[name]; ok {
+			totalImages += n
+		} else {
+			totalImages++
+		}
+	}
+
+	e.coverCopyBeforeId = -1
+	if id, ok := indexedNames[e.coverCopyBefore]; ok && e.coverCopyBefore != "" {
+		e.coverCopyBeforeId = id
+	}
+	return
+}
+
+// read the tiff as if its header pointed to the directory of the page
+type tiffPage struct {
+	r      io.ReaderAt
+	header [8]byte
+}
+
+func (t *tiffPage) ReadAt(p []byte, off int64) (int, error) {
+	n, err := t.r.ReadAt(p, off)
+	for i := off; i < 8 && i < off+int64(n); i++ {
+		p[i-off] = t.header[i]
+	}
+	return n, err
+}
+
+// send each page of a multi-page tiff with its own id, the name is numbered like the pages of a pdf.
+//
+// the preflight only reads the directory of each page, the conversion loads the tiff once for all its pages.
+func (e *EPUBImageProcessor) sendTiffPages(output chan *tasks, id int, path, name string, pages int, open func() (io.ReadCloser, error)) {
+	var r io.ReaderAt
+	var size int64 = math.MaxInt64
+	var bo binary.ByteOrder
+	var offsets []uint32
+	var header [8]byte
+	var readErr error
+	if !e.Dry {
+		if e.Preflight {
+			stream := &tiffStream{open: open}
+			defer stream.Close()
+			r = stream
+		} else {
+			var f io.ReadCloser
+			var data []byte
+			if f, readErr = open(); readErr == nil {
+				data, readErr = io.ReadAll(f)
+				f.Close()
+			}
+			r, size = bytes.NewReader(data), int64(len(data))
+		}
+		if readErr == nil {
+			bo, offsets = tiffDirectories(r)
+			_, readErr = r.ReadAt(header[:], 0)
+		}
+	}
+
+	pageFmt := fmt.Sprintf("%%s page %%0%dd", len(fmt.Sprintf("%d", pages)))
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	for page := 0; page < pages; page++ {
+		t := &tasks{
+			Id:   id + page,
+			Path: path,
+			Name: fmt.Sprintf(pageFmt, base, page+1),
+		}
+		if readErr != nil {
+			t.Error = readErr
+		} else if !e.Dry {
+			p := &tiffPage{r: r, header: header}
+			if page < len(offsets) {
+				bo.PutUint32(p.header[4:8], offsets[page])
+			}
+			sr := io.NewSectionReader(p, 0, size)
+			if e.Preflight {
+				var c image.Config
+				if c, t.Error = tiff.DecodeConfig(sr); t.Error == nil {
+					t.Image = &imageConfig{c}
+				}
+			} else {
+				t.Image, t.Error = tiff.Decode(sr)
+			}
+		}
+		output <- t
+	}
+}
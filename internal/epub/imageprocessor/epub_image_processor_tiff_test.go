@@ -0,0 +1,127 @@
+package epubimageprocessor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+// uncompressed gray tiff with a page by size, the directory of the second page is before the first one
+func multiPageTiff(sizes ...image.Point) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("II\x2a\x00\x00\x00\x00\x00")
+	ifds := make([]uint32, len(sizes))
+	nexts := make([]int, len(sizes))
+	for i := len(sizes) - 1; i >= 0; i-- {
+		w, h := sizes[i].X, sizes[i].Y
+		pixels := uint32(buf.Len())
+		buf.Write(bytes.Repeat([]byte{0x80}, w*h))
+		ifds[i] = uint32(buf.Len())
+		entries := [][2]uint32{{256, uint32(w)}, {257, uint32(h)}, {258, 8}, {259, 1}, {262, 1}, {273, pixels}, {278, uint32(h)}, {279, uint32(w * h)}}
+		binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&buf, binary.LittleEndian, [2]uint16{uint16(e[0]), 4})
+			binary.Write(&buf, binary.LittleEndian, [2]uint32{1, e[1]})
+		}
+		nexts[i] = buf.Len()
+		buf.Write([]byte{0, 0, 0, 0})
+	}
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint32(data[4:8], ifds[0])
+	for i := 0; i+1 < len(sizes); i++ {
+		binary.LittleEndian.PutUint32(data[nexts[i]:], ifds[i+1])
+	}
+	return data
+}
+
+// open the data like a file, or like an entry of an archive that cannot seek, and count the bytes read
+type countingOpen struct {
+	data   []byte
+	stream bool
+	read   int64
+}
+
+func (c *countingOpen) open() (io.ReadCloser, error) {
+	r := &countingReader{Reader: bytes.NewReader(c.data), n: &c.read}
+	if c.stream {
+		return io.NopCloser(struct{ io.Reader }{r}), nil
+	}
+	return r, nil
+}
+
+type countingReader struct {
+	*bytes.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return nil
+}
+
+func TestTiffPageCount(t *testing.T) {
+	data := multiPageTiff(image.Pt(60, 80), image.Pt(100, 50))
+	e := &EPUBImageProcessor{}
+	for _, c := range []struct {
+		name string
+		want int
+	}{
+		{"scan.tif", 2},
+		{"scan.TIFF", 2},
+		{"scan", 2},
+		{"scan.jpg", 1},
+	} {
+		for _, stream := range []bool{false, true} {
+			src := &countingOpen{data: data, stream: stream}
+			if got := e.tiffPageCount(c.name, src.open); got != c.want {
+				t.Errorf("%s: %d pages, want %d", c.name, got, c.want)
+			}
+			// only the header and the directories are read, not the pixels
+			if !stream && src.read > 1000 {
+				t.Errorf("%s: %d bytes read of %d", c.name, src.read, len(data))
+			}
+		}
+	}
+
+	src := &countingOpen{data: []byte("\xff\xd8\xff\xe0 not a tiff")}
+	if got := e.tiffPageCount("scan.tif", src.open); got != 1 {
+		t.Errorf("jpeg named tif: %d pages, want 1", got)
+	}
+}
+
+func TestSendTiffPages(t *testing.T) {
+	data := multiPageTiff(image.Pt(60, 80), image.Pt(100, 50))
+	for _, c := range []struct{ preflight, stream bool }{{false, true}, {true, false}, {true, true}} {
+		e := New(&epuboptions.Options{Preflight: c.preflight, Image: &epuboptions.Image{}})
+		src := &countingOpen{data: data, stream: c.stream}
+		output := make(chan *tasks, 2)
+		e.sendTiffPages(output, 3, "", "scan.tif", 2, src.open)
+		close(output)
+
+		want := []image.Rectangle{image.Rect(0, 0, 60, 80), image.Rect(0, 0, 100, 50)}
+		names := []string{"scan page 1", "scan page 2"}
+		i := 0
+		for task := range output {
+			if task.Error != nil {
+				t.Fatalf("preflight %v, stream %v: %v", c.preflight, c.stream, task.Error)
+			}
+			if task.Id != 3+i || task.Name != names[i] || task.Image.Bounds() != want[i] {
+				t.Errorf("preflight %v, stream %v: page %d is %d %q %v", c.preflight, c.stream, i, task.Id, task.Name, task.Image.Bounds())
+			}
+			i++
+		}
+		// the preflight only reads the directories of a file
+		if c.preflight && !c.stream && src.read > 1000 {
+			t.Errorf("preflight: %d bytes read of %d", src.read, len(data))
+		}
+	}
+}
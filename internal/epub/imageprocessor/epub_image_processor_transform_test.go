@@ -0,0 +1,117 @@
+package epubimageprocessor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+// double page of thin vertical stripes, blurred by any low-pass
+func stripesSpread(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x%4 < 2 {
+				img.SetGray(x, y, color.Gray{0xff})
+			}
+		}
+	}
+	return img
+}
+
+func splitProcessor(descreen bool) *EPUBImageProcessor {
+	return New(&epuboptions.Options{Image: &epuboptions.Image{
+		Crop:                &epuboptions.Crop{},
+		Resize:              true,
+		AutoSplitDoublePage: true,
+		Descreen:            descreen,
+		Gamma:               1,
+		View:                &epuboptions.View{Width: 100, Height: 100},
+	}})
+}
+
+func TestDescreenSplitHalves(t *testing.T) {
+	src := stripesSpread(200, 100)
+	plain := splitProcessor(false).transformImage(src, 1, true)
+	descreened := splitProcessor(true).transformImage(src, 1, true)
+	if len(plain) != 3 || len(descreened) != 3 {
+		t.Fatalf("got %d and %d images, want 3", len(plain), len(descreened))
+	}
+
+	// the spread is halved, so it is low-passed
+	if bytes.Equal(plain[0].(*image.Gray).Pix, descreened[0].(*image.Gray).Pix) {
+		t.Error("spread not descreened")
+	}
+
+	// each half fits the view as is, so it is kept sharp
+	for i := 1; i < 3; i++ {
+		if !bytes.Equal(plain[i].(*image.Gray).Pix, descreened[i].(*image.Gray).Pix) {
+			t.Errorf("half %d descreened with the ratio of the spread", i)
+		}
+	}
+}
+
+// darkest and lightest gray of a page
+func levels(img *image.Gray) (lo, hi uint8) {
+	lo = 0xff
+	for _, p := range img.Pix {
+		lo, hi = min(lo, p), max(hi, p)
+	}
+	return
+}
+
+func TestAutoContrastSplitHalves(t *testing.T) {
+	// a faded page beside a page with the full levels
+	src := image.NewGray(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			src.SetGray(x, y, color.Gray{uint8(100 + x/2)})
+			src.SetGray(100+x, y, color.Gray{uint8(x * 255 / 99)})
+		}
+	}
+
+	e := splitProcessor(false)
+	e.Image.AutoContrast = true
+	images := e.transformImage(src, 1, true)
+	if len(images) != 3 {
+		t.Fatalf("got %d images, want 3", len(images))
+	}
+	for i := 1; i < 3; i++ {
+		if lo, hi := levels(images[i].(*image.Gray)); lo > 2 || hi < 253 {
+			t.Errorf("half %d levels %d-%d, want stretched to 0-255", i, lo, hi)
+		}
+	}
+}
+
+func TestSplitReadingDirection(t *testing.T) {
+	// dark left page, light right page
+	src := image.NewGray(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 100; x < 200; x++ {
+			src.SetGray(x, y, color.Gray{0xff})
+		}
+	}
+
+	for _, c := range []struct {
+		manga bool
+		want  []uint8 // gray of the parts 1 and 2
+	}{
+		{false, []uint8{0, 0xff}},
+		{true, []uint8{0xff, 0}},
+	} {
+		e := splitProcessor(false)
+		e.Image.Manga = c.manga
+		images := e.transformImage(src, 1, true)
+		if len(images) != 3 {
+			t.Fatalf("manga %v: got %d images, want 3", c.manga, len(images))
+		}
+		for part, want := range c.want {
+			if lo, hi := levels(images[part+1].(*image.Gray)); lo != want || hi != want {
+				t.Errorf("manga %v: part %d has the grays %d-%d, want %d", c.manga, part+1, lo, hi, want)
+			}
+		}
+	}
+}
@@ -3,11 +3,19 @@ Options for EPUB creation.
 */
 package epuboptions
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
 
 type Crop struct {
 	Enabled                 bool
+	FromFirst               bool
 	Left, Up, Right, Bottom int
+	Threshold               int
+	MinRatio                float64
+	Padding                 int
 }
 
 type Color struct {
@@ -24,34 +32,113 @@ type View struct {
 type Image struct {
 	Crop                *Crop
 	Quality             int
+	Qualities           []int
 	Brightness          int
 	Contrast            int
+	AutoContrast        bool
+	AutoContrastClip    float64
+	Gamma               float64
+	Sharpen             bool
+	SharpenAmount       float64
+	Dither              bool
+	DitherLevels        int
 	AutoRotate          bool
 	AutoSplitDoublePage bool
+	SpreadMode          string
+	DoublePageRatio     float64
+	LongStrip           bool
+	LongStripHeight     int
 	NoBlankImage        bool
 	Manga               bool
+	AutoDirection       bool
 	HasCover            bool
+	CoverFit            string
+	CoverBack           int
+	CoverRatio          float64
 	View                *View
 	GrayScale           bool
 	GrayScaleMode       int
 	Resize              bool
+	BoxRatio            float64
+	ResizeFilter        string
+	FitMode             string
+	PadColor            string
 	Format              string
+	Lossless            bool
+	LosslessCover       bool
+	BytesPerPage        int
+	RawPages            bool
+	Deskew              bool
+	Descreen            bool
+	ScaledDecode        bool
+}
+
+// format of the cover image
+func (i *Image) CoverFormat() string {
+	if i.LosslessCover {
+		return "png"
+	}
+	return i.Format
 }
 
 type Options struct {
 	Input                      string
 	Output                     string
+	TmpDir                     string
 	Title                      string
+	Identifier                 string
 	TitlePage                  int
+	VolumeInfoPage             bool
+	BlankPage                  int
 	Author                     string
+	Series                     string
+	Volume                     string
+	Year                       string
+	OwnerTag                   string
+	Language                   string
+	Publisher                  string
 	LimitMb                    int
+	LimitFiles                 int
+	PartFormat                 string
+	NoZip64                    bool
+	LimitExcludeCover          bool
 	StripFirstDirectoryFromToc bool
+	MinChapterPages            int
+	TocThumbnails              bool
 	Dry                        bool
 	DryVerbose                 bool
+	Preflight                  bool
+	CheckArchive               bool
+	SkipBad                    bool
 	SortPathMode               int
+	ReverseOrder               bool
+	IncludeHidden              bool
+	WarnUnsupported            bool
+	SniffContent               bool
+	NoCoverFile                bool
+	CoverSelector              string
+	CoverOnly                  bool
+	RarMode                    string
+	Password                   string
+	Order                      string
+	Sample                     int
+	SamplePages                int
+	KeepPages                  [][2]int // first and last page, starting at 1, last = 0 until the end
+	PdfDpi                     int
+	PdfRender                  string
+	Colophon                   string
+	ArchiveCbz                 string
 	Quiet                      bool
 	Workers                    int
+	Readers                    int
+	Decoders                   int
+	Target                     string
+	Panels                     string
 	Image                      *Image
+
+	// called by the workers after each page processed, with the number of pages done out of the total.
+	// the calls can be concurrent, done only grows but a call can arrive after a later one.
+	OnProgress func(done, total int)
 }
 
 func (o *Options) WorkersRatio(pct int) (nbWorkers int) {
@@ -62,6 +149,33 @@ func (o *Options) WorkersRatio(pct int) (nbWorkers int) {
 	return
 }
 
+// number of readers of the files of a directory, half of the workers by default
+func (o *Options) ReadersCount() int {
+	if o.Readers > 0 {
+		return o.Readers
+	}
+	return o.WorkersRatio(50)
+}
+
+// number of decoders of the files of a directory, half of the workers by default
+func (o *Options) DecodersCount() int {
+	if o.Decoders > 0 {
+		return o.Decoders
+	}
+	return o.WorkersRatio(50)
+}
+
+// path of the converted images before writing the EPUB.
+//
+// it is unique to the run if a temporary directory is set, so parallel conversions don't collide.
 func (o *Options) ImgStorage() string {
+	if o.TmpDir != "" {
+		return filepath.Join(o.TmpDir, "images.tmp")
+	}
 	return fmt.Sprintf("%s.tmp", o.Output)
 }
+
+// path of the images converted with another quality
+func (o *Options) QualityImgStorage(quality int) string {
+	return fmt.Sprintf("%s.q%d.tmp", strings.TrimSuffix(o.ImgStorage(), ".tmp"), quality)
+}
@@ -20,4 +20,7 @@ var (
 
 	//go:embed "epub_templates_blank.xhtml.tmpl"
 	Blank string
+
+	//go:embed "epub_templates_info.xhtml.tmpl"
+	Info string
 )
@@ -2,6 +2,8 @@ package epubtemplates
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 
 	"github.com/beevik/etree"
 	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
@@ -11,13 +13,23 @@ import (
 type ContentOptions struct {
 	Title        string
 	HasTitlePage bool
+	HasInfoPage  bool
+	AppleBooks   bool
+	Kobo         bool
+	HasRegions   bool
 	UID          string
 	Author       string
+	Series       string
+	Volume       string
+	Year         string
+	Language     string
 	Publisher    string
+	OwnerTag     string
 	UpdatedAt    string
 	ImageOptions *epuboptions.Image
 	Cover        *epubimage.Image
 	Images       []*epubimage.Image
+	Thumbnails   map[*epubimage.Image]bool
 	Current      int
 	Total        int
 }
@@ -63,6 +75,7 @@ func Content(o *ContentOptions) string {
 	addToElement(manifest, getManifest)
 
 	spine := pkg.CreateElement("spine")
+	spine.CreateAttr("toc", "ncx")
 	if o.ImageOptions.Manga {
 		spine.CreateAttr("page-progression-direction", "rtl")
 	} else {
@@ -86,6 +99,12 @@ func Content(o *ContentOptions) string {
 
 // metadata part of the content
 func getMeta(o *ContentOptions) []tag {
+	// publication year, the date of the conversion otherwise
+	date := o.UpdatedAt
+	if o.Year != "" {
+		date = o.Year
+	}
+
 	metas := []tag{
 		{"meta", tagAttrs{"property": "dcterms:modified"}, o.UpdatedAt},
 		{"meta", tagAttrs{"property": "schema:accessMode"}, "visual"},
@@ -97,12 +116,14 @@ func getMeta(o *ContentOptions) []tag {
 		{"opf:meta", tagAttrs{"name": "fixed-layout", "content": "true"}, ""},
 		{"opf:meta", tagAttrs{"name": "original-resolution", "content": fmt.Sprintf("%dx%d", o.ImageOptions.View.Width, o.ImageOptions.View.Height)}, ""},
 		{"dc:title", tagAttrs{}, o.Title},
-		{"dc:identifier", tagAttrs{"id": "ean"}, fmt.Sprintf("urn:uuid:%s", o.UID)},
-		{"dc:language", tagAttrs{}, "en"},
+		{"dc:identifier", tagAttrs{"id": "ean"}, o.UID},
+		{"dc:language", tagAttrs{}, o.Language},
 		{"dc:creator", tagAttrs{}, o.Author},
-		{"dc:publisher", tagAttrs{}, o.Publisher},
 		{"dc:contributor", tagAttrs{}, "Go Comic Convertor"},
-		{"dc:date", tagAttrs{}, o.UpdatedAt},
+		{"dc:date", tagAttrs{}, date},
+	}
+	if o.Publisher != "" {
+		metas = append(metas, tag{"dc:publisher", tagAttrs{}, o.Publisher})
 	}
 
 	if o.ImageOptions.View.PortraitOnly {
@@ -111,6 +132,13 @@ func getMeta(o *ContentOptions) []tag {
 			{"meta", tagAttrs{"property": "rendition:spread"}, "none"},
 			{"meta", tagAttrs{"property": "rendition:orientation"}, "portrait"},
 		}...)
+	} else if o.AppleBooks {
+		// Apple Books only display spread in landscape
+		metas = append(metas, []tag{
+			{"meta", tagAttrs{"property": "rendition:layout"}, "pre-paginated"},
+			{"meta", tagAttrs{"property": "rendition:spread"}, "landscape"},
+			{"meta", tagAttrs{"property": "rendition:orientation"}, "auto"},
+		}...)
 	} else {
 		metas = append(metas, []tag{
 			{"meta", tagAttrs{"property": "rendition:layout"}, "pre-paginated"},
@@ -127,7 +155,25 @@ func getMeta(o *ContentOptions) []tag {
 
 	metas = append(metas, tag{"meta", tagAttrs{"name": "cover", "content": "img_cover"}, ""})
 
-	if o.Total > 1 {
+	// informative only, this is not a DRM
+	if o.OwnerTag != "" {
+		metas = append(metas, tag{"meta", tagAttrs{"name": "go-comic-converter:owner", "content": o.OwnerTag}, ""})
+	}
+
+	if o.Series != "" {
+		position, index := seriesIndex(o)
+		metas = append(metas, tag{"meta", tagAttrs{"property": "belongs-to-collection", "id": "series"}, o.Series})
+		metas = append(metas, tag{"meta", tagAttrs{"refines": "#series", "property": "collection-type"}, "series"})
+		if position != "" {
+			metas = append(metas, tag{"meta", tagAttrs{"refines": "#series", "property": "group-position"}, position})
+		}
+
+		// legacy form read by calibre, the index should be a number
+		metas = append(metas, tag{"meta", tagAttrs{"name": "calibre:series", "content": o.Series}, ""})
+		if index {
+			metas = append(metas, tag{"meta", tagAttrs{"name": "calibre:series_index", "content": position}, ""})
+		}
+	} else if o.Total > 1 {
 		metas = append(
 			metas,
 			tag{"meta", tagAttrs{"name": "calibre:series", "content": o.Title}, ""},
@@ -138,6 +184,26 @@ func getMeta(o *ContentOptions) []tag {
 	return metas
 }
 
+// position of the part in the series, and if it is a number.
+//
+// the parts of a split volume follow the volume, like 3, 3.33 and 3.67 for the 3 parts of the volume 3,
+// and they are numbered from 1 without volume.
+func seriesIndex(o *ContentOptions) (string, bool) {
+	volume, err := strconv.ParseFloat(o.Volume, 64)
+	switch {
+	case o.Volume != "" && err != nil:
+		return o.Volume, false
+	case o.Total <= 1 && o.Volume == "":
+		return "", false
+	case o.Total <= 1:
+		return strconv.FormatFloat(volume, 'f', -1, 64), true
+	case o.Volume == "":
+		return fmt.Sprint(o.Current), true
+	}
+	index := volume + float64(o.Current-1)/float64(o.Total)
+	return strconv.FormatFloat(math.Round(index*100)/100, 'f', -1, 64), true
+}
+
 func getManifest(o *ContentOptions) []tag {
 	var imageTags, pageTags, spaceTags []tag
 	addTag := func(img *epubimage.Image, withSpace bool) {
@@ -154,11 +220,22 @@ func getManifest(o *ContentOptions) []tag {
 		}
 	}
 
+	// the kobo library only shows the cover of a kepub declared with its epub 3 property
+	coverAttrs := tagAttrs{"id": "img_cover", "href": fmt.Sprintf("Images/cover.%s", o.ImageOptions.CoverFormat()), "media-type": fmt.Sprintf("image/%s", o.ImageOptions.CoverFormat())}
+	if o.Kobo {
+		coverAttrs["properties"] = "cover-image"
+	}
+
 	items := []tag{
 		{"item", tagAttrs{"id": "toc", "href": "toc.xhtml", "properties": "nav", "media-type": "application/xhtml+xml"}, ""},
+		{"item", tagAttrs{"id": "ncx", "href": "toc.ncx", "media-type": "application/x-dtbncx+xml"}, ""},
 		{"item", tagAttrs{"id": "css", "href": "Text/style.css", "media-type": "text/css"}, ""},
 		{"item", tagAttrs{"id": "page_cover", "href": "Text/cover.xhtml", "media-type": "application/xhtml+xml"}, ""},
-		{"item", tagAttrs{"id": "img_cover", "href": fmt.Sprintf("Images/cover.%s", o.ImageOptions.Format), "media-type": fmt.Sprintf("image/%s", o.ImageOptions.Format)}, ""},
+		{"item", coverAttrs, ""},
+	}
+
+	if o.HasRegions {
+		items = append(items, tag{"item", tagAttrs{"id": "regions", "href": "regions.xhtml", "properties": "data-nav", "media-type": "application/xhtml+xml"}, ""})
 	}
 
 	if o.HasTitlePage {
@@ -172,9 +249,21 @@ func getManifest(o *ContentOptions) []tag {
 		}
 	}
 
+	if o.HasInfoPage {
+		items = append(items, tag{"item", tagAttrs{"id": "page_info", "href": "Text/info.xhtml", "media-type": "application/xhtml+xml"}, ""})
+	}
+
 	lastImage := o.Images[len(o.Images)-1]
 	for _, img := range o.Images {
+		if img.BlankBefore {
+			items = append(items, tag{"item", tagAttrs{"id": "page_blank", "href": "Text/blank.xhtml", "media-type": "application/xhtml+xml"}, ""})
+		}
 		addTag(img, !o.ImageOptions.View.PortraitOnly && (img.DoublePage || (img.Part == 0 && img == lastImage)))
+		if o.Thumbnails[img] {
+			imageTags = append(imageTags,
+				tag{"item", tagAttrs{"id": img.ThumbKey(), "href": img.ThumbPath(), "media-type": fmt.Sprintf("image/%s", o.ImageOptions.Format)}, ""},
+			)
+		}
 	}
 
 	items = append(items, imageTags...)
@@ -211,7 +300,13 @@ func getSpineAuto(o *ContentOptions) []tag {
 			tag{"itemref", tagAttrs{"idref": "page_title", "properties": getSpread(false)}, ""},
 		)
 	}
+	if o.HasInfoPage {
+		spine = append(spine, tag{"itemref", tagAttrs{"idref": "page_info", "properties": getSpread(false)}, ""})
+	}
 	for _, img := range o.Images {
+		if img.BlankBefore {
+			spine = append(spine, tag{"itemref", tagAttrs{"idref": "page_blank", "properties": getSpreadBlank()}, ""})
+		}
 		if img.DoublePage && o.ImageOptions.Manga == isOnTheRight {
 			spine = append(spine, tag{
 				"itemref",
@@ -245,6 +340,9 @@ func getSpinePortrait(o *ContentOptions) []tag {
 			tag{"itemref", tagAttrs{"idref": "page_title"}, ""},
 		)
 	}
+	if o.HasInfoPage {
+		spine = append(spine, tag{"itemref", tagAttrs{"idref": "page_info"}, ""})
+	}
 	for _, img := range o.Images {
 		spine = append(spine, tag{
 			"itemref",
@@ -0,0 +1,180 @@
+package epubtemplates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
+)
+
+func metaOptions() *ContentOptions {
+	return &ContentOptions{
+		Title:        "Book",
+		UpdatedAt:    "2024-01-02T03:04:05Z",
+		ImageOptions: &epuboptions.Image{Format: "jpeg", View: &epuboptions.View{Width: 1200, Height: 1600}},
+	}
+}
+
+// values of the tags with this name
+func metaValues(metas []tag, name string) []string {
+	var values []string
+	for _, m := range metas {
+		if m.name == name {
+			values = append(values, m.value)
+		}
+	}
+	return values
+}
+
+func TestMetaDate(t *testing.T) {
+	for _, c := range []struct {
+		year, want string
+	}{
+		{"", "2024-01-02T03:04:05Z"},
+		{"1987", "1987"},
+	} {
+		o := metaOptions()
+		o.Year = c.year
+		got := metaValues(getMeta(o), "dc:date")
+		if len(got) != 1 || got[0] != c.want {
+			t.Errorf("year %q: dc:date = %v, want [%s]", c.year, got, c.want)
+		}
+	}
+}
+
+func TestManifestKoboCover(t *testing.T) {
+	for _, kobo := range []bool{false, true} {
+		o := metaOptions()
+		o.Kobo = kobo
+		o.Images = []*epubimage.Image{{Id: 1, Format: "jpeg"}}
+		found := false
+		for _, item := range getManifest(o) {
+			if item.attrs["id"] != "img_cover" {
+				continue
+			}
+			found = true
+			if got := item.attrs["properties"] == "cover-image"; got != kobo {
+				t.Errorf("kobo %v: cover properties %q", kobo, item.attrs["properties"])
+			}
+		}
+		if !found {
+			t.Errorf("kobo %v: no cover in the manifest", kobo)
+		}
+	}
+}
+
+func TestMetaSeriesIndex(t *testing.T) {
+	for _, c := range []struct {
+		volume         string
+		current, total int
+		want           []string
+	}{
+		{"", 1, 1, nil},
+		{"0", 1, 1, []string{"0"}},
+		{"3", 1, 1, []string{"3"}},
+		{"3", 1, 3, []string{"3"}},
+		{"3", 2, 3, []string{"3.33"}},
+		{"3", 3, 3, []string{"3.67"}},
+		{"", 2, 3, []string{"2"}},
+		{"III", 2, 3, nil},
+	} {
+		o := metaOptions()
+		o.Series, o.Volume, o.Current, o.Total = "Serie", c.volume, c.current, c.total
+		var got []string
+		for _, m := range getMeta(o) {
+			if m.attrs["name"] == "calibre:series_index" {
+				got = append(got, m.attrs["content"])
+			}
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("volume %q part %d/%d: series index %v, want %v", c.volume, c.current, c.total, got, c.want)
+		}
+	}
+}
+
+func TestMetaPublisher(t *testing.T) {
+	for _, publisher := range []string{"", "Editor"} {
+		o := metaOptions()
+		o.Publisher = publisher
+		got := metaValues(getMeta(o), "dc:publisher")
+		if (publisher == "" && len(got) != 0) || (publisher != "" && !reflect.DeepEqual(got, []string{publisher})) {
+			t.Errorf("publisher %q: dc:publisher = %v", publisher, got)
+		}
+	}
+}
+
+// content of a book with the pages, a double page for each true
+func bookOptions(manga bool, doublePages ...bool) *ContentOptions {
+	o := metaOptions()
+	o.ImageOptions.Manga = manga
+	for i, double := range doublePages {
+		o.Images = append(o.Images, &epubimage.Image{Id: i + 1, Format: "jpeg", DoublePage: double})
+	}
+	return o
+}
+
+func TestContentReadingDirection(t *testing.T) {
+	for _, c := range []struct {
+		manga bool
+		want  string
+	}{
+		{false, `<spine toc="ncx" page-progression-direction="ltr">`},
+		{true, `<spine toc="ncx" page-progression-direction="rtl">`},
+	} {
+		if opf := Content(bookOptions(c.manga, false, false)); !strings.Contains(opf, c.want) {
+			t.Errorf("manga %v: no %s in\n%s", c.manga, c.want, opf)
+		}
+	}
+}
+
+func TestContentFixedLayout(t *testing.T) {
+	opf := Content(bookOptions(false, false, false))
+	for _, want := range []string{
+		`<meta property="rendition:layout">pre-paginated</meta>`,
+		`<meta property="rendition:spread">auto</meta>`,
+		`<opf:meta content="true" name="fixed-layout"/>`,
+		`<opf:meta content="1200x1600" name="original-resolution"/>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("no %s in\n%s", want, opf)
+		}
+	}
+}
+
+func TestSpineSpreads(t *testing.T) {
+	const (
+		left   = "rendition:page-spread-left"
+		right  = "rendition:page-spread-right"
+		center = "rendition:page-spread-center"
+	)
+	for _, c := range []struct {
+		name        string
+		manga       bool
+		doublePages []bool
+		want        []string // idref=properties of the spine
+	}{
+		{"ltr", false, []bool{false, false, false}, []string{
+			"page_1_p0=" + left, "page_2_p0=" + right, "page_3_p0=" + left, "space_3=" + right,
+		}},
+		{"rtl", true, []bool{false, false, false}, []string{
+			"page_1_p0=" + right, "page_2_p0=" + left, "page_3_p0=" + right, "space_3=" + left,
+		}},
+		// a blank page realigns the spread before it is centered
+		{"ltr spread", false, []bool{false, true, false}, []string{
+			"page_1_p0=" + left, "space_2=" + right + " layout-blank", "page_2_p0=" + center, "page_3_p0=" + left, "space_3=" + right,
+		}},
+		{"rtl spread", true, []bool{false, true, false}, []string{
+			"page_1_p0=" + right, "space_2=" + left + " layout-blank", "page_2_p0=" + center, "page_3_p0=" + right, "space_3=" + left,
+		}},
+	} {
+		var got []string
+		for _, item := range getSpineAuto(bookOptions(c.manga, c.doublePages...)) {
+			got = append(got, item.attrs["idref"]+"="+item.attrs["properties"])
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: spine\n%q\nwant\n%q", c.name, got, c.want)
+		}
+	}
+}
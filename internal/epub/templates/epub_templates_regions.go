@@ -0,0 +1,45 @@
+package epubtemplates
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/beevik/etree"
+	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
+)
+
+// create the region based navigation
+//
+// panels are indexed by the source path of the image, each panel is a box x,y,w,h in percent of the page.
+func Regions(title string, images []*epubimage.Image, panels map[string][][]float64) string {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	doc.CreateDirective("DOCTYPE html")
+
+	html := doc.CreateElement("html")
+	html.CreateAttr("xmlns", "http://www.w3.org/1999/xhtml")
+	html.CreateAttr("xmlns:epub", "http://www.idpf.org/2007/ops")
+
+	html.CreateElement("head").CreateElement("title").CreateText(title)
+	body := html.CreateElement("body")
+	nav := body.CreateElement("nav")
+	nav.CreateAttr("epub:type", "region-based")
+	nav.CreateAttr("id", "regions")
+
+	ol := nav.CreateElement("ol")
+	for _, img := range images {
+		if img.Part != 0 {
+			continue
+		}
+		for _, p := range panels[filepath.ToSlash(filepath.Join(img.Path, img.Name))] {
+			li := ol.CreateElement("li")
+			li.CreateAttr("epub:type", "panel")
+			a := li.CreateElement("a")
+			a.CreateAttr("href", fmt.Sprintf("%s#xywh=percent:%g,%g,%g,%g", img.PagePath(), p[0], p[1], p[2], p[3]))
+		}
+	}
+
+	doc.Indent(2)
+	r, _ := doc.WriteToString()
+	return r
+}
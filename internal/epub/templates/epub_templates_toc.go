@@ -1,6 +1,7 @@
 package epubtemplates
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -8,8 +9,47 @@ import (
 	epubimage "github.com/celogeek/go-comic-converter/v2/internal/epub/image"
 )
 
+// path of the chapter of each image.
+//
+// chapters with less than minChapterPages pages are merged into the previous one.
+func chapterPaths(images []*epubimage.Image, minChapterPages int) []string {
+	paths := make([]string, len(images))
+	pages := map[string]int{}
+	for _, img := range images {
+		if img.Part == 0 {
+			pages[img.Path]++
+		}
+	}
+
+	for i, img := range images {
+		switch {
+		case i > 0 && img.Path == images[i-1].Path:
+			paths[i] = paths[i-1]
+		case i > 0 && pages[img.Path] < minChapterPages:
+			paths[i] = paths[i-1]
+		default:
+			paths[i] = img.Path
+		}
+	}
+	return paths
+}
+
+// first image of each chapter
+func ChapterStarts(images []*epubimage.Image, minChapterPages int) []*epubimage.Image {
+	starts := []*epubimage.Image{}
+	paths := chapterPaths(images, minChapterPages)
+	for i, img := range images {
+		if i == 0 || paths[i] != paths[i-1] {
+			starts = append(starts, img)
+		}
+	}
+	return starts
+}
+
 // create toc
-func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, images []*epubimage.Image) string {
+//
+// the chapters link to the thumbnail of their first image if provided.
+func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, minChapterPages int, images []*epubimage.Image, thumbnails map[*epubimage.Image]bool) string {
 	doc := etree.NewDocument()
 	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
 	doc.CreateDirective("DOCTYPE html")
@@ -24,12 +64,91 @@ func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, images []
 	nav.CreateAttr("epub:type", "toc")
 	nav.CreateAttr("id", "toc")
 	nav.CreateElement("h2").CreateText(title)
+	nav.AddChild(tocList(title, hasTitle, stripFirstDirectoryFromToc, minChapterPages, images, thumbnails))
 
+	doc.Indent(2)
+	r, _ := doc.WriteToString()
+	return r
+}
+
+// create the toc in the NCX format of EPUB 2, for the readers which ignore the nav.
+//
+// same entries as the nav, without the thumbnails.
+func TocNcx(uid string, title string, hasTitle bool, stripFirstDirectoryFromToc bool, minChapterPages int, images []*epubimage.Image) string {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+
+	ncx := doc.CreateElement("ncx")
+	ncx.CreateAttr("xmlns", "http://www.daisy.org/z3986/2005/ncx/")
+	ncx.CreateAttr("version", "2005-1")
+
+	head := ncx.CreateElement("head")
+	ncx.CreateElement("docTitle").CreateElement("text").CreateText(title)
+	navMap := ncx.CreateElement("navMap")
+
+	// the entries with the same page share the same play order
+	playOrders := map[string]int{}
+	count, depth := 0, 0
+	var addNavPoints func(parent *etree.Element, ol *etree.Element, level int)
+	addNavPoints = func(parent *etree.Element, ol *etree.Element, level int) {
+		depth = max(depth, level)
+		for _, li := range ol.SelectElements("li") {
+			a := li.SelectElement("a")
+			src := a.SelectAttrValue("href", "")
+			if _, ok := playOrders[src]; !ok {
+				playOrders[src] = len(playOrders) + 1
+			}
+			count++
+
+			navPoint := parent.CreateElement("navPoint")
+			navPoint.CreateAttr("id", fmt.Sprintf("nav_%d", count))
+			navPoint.CreateAttr("playOrder", fmt.Sprint(playOrders[src]))
+			navPoint.CreateElement("navLabel").CreateElement("text").CreateText(linkText(a))
+			navPoint.CreateElement("content").CreateAttr("src", src)
+			if sub := li.SelectElement("ol"); sub != nil {
+				addNavPoints(navPoint, sub, level+1)
+			}
+		}
+	}
+	addNavPoints(navMap, tocList(title, hasTitle, stripFirstDirectoryFromToc, minChapterPages, images, nil), 1)
+
+	for _, meta := range [][2]string{
+		{"dtb:uid", uid},
+		{"dtb:depth", fmt.Sprint(depth)},
+		{"dtb:totalPageCount", "0"},
+		{"dtb:maxPageNumber", "0"},
+	} {
+		m := head.CreateElement("meta")
+		m.CreateAttr("name", meta[0])
+		m.CreateAttr("content", meta[1])
+	}
+
+	doc.Indent(2)
+	r, _ := doc.WriteToString()
+	return r
+}
+
+// text of a link, after its thumbnail
+func linkText(a *etree.Element) string {
+	text := ""
+	for _, c := range a.Child {
+		if d, ok := c.(*etree.CharData); ok {
+			text += d.Data
+		}
+	}
+	return text
+}
+
+// list of the chapters, one entry per directory.
+//
+// the list starts with the title page (or the first image), which also holds the images at the root.
+func tocList(title string, hasTitle bool, stripFirstDirectoryFromToc bool, minChapterPages int, images []*epubimage.Image, thumbnails map[*epubimage.Image]bool) *etree.Element {
 	ol := etree.NewElement("ol")
 	paths := map[string]*etree.Element{".": ol}
-	for _, img := range images {
+	for i, chapterPath := range chapterPaths(images, minChapterPages) {
+		img := images[i]
 		currentPath := "."
-		for _, path := range strings.Split(img.Path, string(filepath.Separator)) {
+		for _, path := range strings.Split(chapterPath, string(filepath.Separator)) {
 			parentPath := currentPath
 			currentPath = filepath.Join(currentPath, path)
 			if _, ok := paths[currentPath]; ok {
@@ -38,6 +157,11 @@ func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, images []
 			t := paths[parentPath].CreateElement("li")
 			link := t.CreateElement("a")
 			link.CreateAttr("href", img.PagePath())
+			if thumbnails[img] {
+				thumb := link.CreateElement("img")
+				thumb.CreateAttr("src", img.ThumbPath())
+				thumb.CreateAttr("alt", "")
+			}
 			link.CreateText(path)
 			paths[currentPath] = t.CreateElement("ol")
 		}
@@ -63,9 +187,5 @@ func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, images []
 	beginningLink.CreateText(title)
 	ol.InsertChildAt(0, beginning)
 
-	nav.AddChild(ol)
-
-	doc.Indent(2)
-	r, _ := doc.WriteToString()
-	return r
+	return ol
 }
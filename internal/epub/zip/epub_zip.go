@@ -7,10 +7,19 @@ package epubzip
 
 import (
 	"archive/zip"
+	"io"
 	"os"
 	"time"
 )
 
+// Limits of a zip without the zip64 extension, not supported by some readers.
+//
+// The writer switches to zip64 automatically above them.
+const (
+	MaxSizeWithoutZip64    = 1<<32 - 1
+	MaxEntriesWithoutZip64 = 1<<16 - 1
+)
+
 type EPUBZip struct {
 	w  *os.File
 	wz *zip.Writer
@@ -62,6 +71,22 @@ func (e *EPUBZip) Copy(fz *zip.File) error {
 	return e.wz.Copy(fz)
 }
 
+// Copy a file under another name, without decompressing it.
+func (e *EPUBZip) CopyAs(fz *zip.File, name string) error {
+	r, err := fz.OpenRaw()
+	if err != nil {
+		return err
+	}
+	fh := fz.FileHeader
+	fh.Name = name
+	w, err := e.wz.CreateRaw(&fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
 // Write image. They are already compressed, so we write them down directly.
 func (e *EPUBZip) WriteRaw(raw *ZipImage) error {
 	m, err := e.wz.CreateRaw(raw.Header)
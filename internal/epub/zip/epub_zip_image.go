@@ -10,6 +10,8 @@ import (
 	"image/jpeg"
 	"image/png"
 	"time"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/webpencode"
 )
 
 type ZipImage struct {
@@ -17,11 +19,50 @@ type ZipImage struct {
 	Data   []byte
 }
 
+// lowest quality used to reach a size budget
+const MinBudgetQuality = 30
+
+// create gzip encoded jpeg (or lossy webp) with the highest quality that fit the budget (in bytes).
+//
+// the quality is searched between MinBudgetQuality and quality, the lowest one is used if the budget can't be reached.
+// png and lossless webp always use the plain compression.
+func CompressImageBudget(filename string, format string, img image.Image, quality int, lossless bool, budget uint64) (*ZipImage, error) {
+	if !(format == "jpeg" || format == "webp" && !lossless) || budget == 0 || quality <= MinBudgetQuality {
+		return CompressImage(filename, format, img, quality, lossless)
+	}
+
+	best, err := CompressImage(filename, format, img, quality, lossless)
+	if err != nil || best.Header.CompressedSize64 <= budget {
+		return best, err
+	}
+
+	lo, hi := MinBudgetQuality, quality-1
+	best = nil
+	for lo <= hi {
+		q := (lo + hi) / 2
+		z, err := CompressImage(filename, format, img, q, lossless)
+		if err != nil {
+			return nil, err
+		}
+		if z.Header.CompressedSize64 <= budget {
+			best, lo = z, q+1
+		} else {
+			hi = q - 1
+		}
+	}
+	if best == nil {
+		return CompressImage(filename, format, img, MinBudgetQuality, lossless)
+	}
+	return best, nil
+}
+
 // create gzip encoded jpeg
-func CompressImage(filename string, format string, img image.Image, quality int) (*ZipImage, error) {
+//
+// lossless only applies to webp, png is always lossless and jpeg never.
+func CompressImage(filename string, format string, img image.Image, quality int, lossless bool) (*ZipImage, error) {
 	var (
-		data, cdata bytes.Buffer
-		err         error
+		data bytes.Buffer
+		err  error
 	)
 
 	switch format {
@@ -29,6 +70,8 @@ func CompressImage(filename string, format string, img image.Image, quality int)
 		err = png.Encode(&data, img)
 	case "jpeg":
 		err = jpeg.Encode(&data, img, &jpeg.Options{Quality: quality})
+	case "webp":
+		err = webpencode.Encode(&data, img, quality, lossless)
 	default:
 		err = fmt.Errorf("unknown format %q", format)
 	}
@@ -36,12 +79,18 @@ func CompressImage(filename string, format string, img image.Image, quality int)
 		return nil, err
 	}
 
+	return CompressRaw(filename, data.Bytes())
+}
+
+// create gzip encoded image from already encoded data
+func CompressRaw(filename string, data []byte) (*ZipImage, error) {
+	var cdata bytes.Buffer
 	wcdata, err := flate.NewWriter(&cdata, flate.BestCompression)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = wcdata.Write(data.Bytes())
+	_, err = wcdata.Write(data)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +105,8 @@ func CompressImage(filename string, format string, img image.Image, quality int)
 		&zip.FileHeader{
 			Name:               filename,
 			CompressedSize64:   uint64(cdata.Len()),
-			UncompressedSize64: uint64(data.Len()),
-			CRC32:              crc32.Checksum(data.Bytes(), crc32.IEEETable),
+			UncompressedSize64: uint64(len(data)),
+			CRC32:              crc32.Checksum(data, crc32.IEEETable),
 			Method:             zip.Deflate,
 			ModifiedTime:       uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11),
 			ModifiedDate:       uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9),
@@ -8,19 +8,22 @@ import (
 )
 
 type EPUBZipStorageImageWriter struct {
-	fh     *os.File
-	fz     *zip.Writer
-	format string
-	mut    *sync.Mutex
+	fh       *os.File
+	fz       *zip.Writer
+	format   string
+	lossless bool
+	budget   uint64
+	mut      *sync.Mutex
 }
 
-func NewEPUBZipStorageImageWriter(filename string, format string) (*EPUBZipStorageImageWriter, error) {
+// budget is the size limit of each image in bytes, 0 = no limit
+func NewEPUBZipStorageImageWriter(filename string, format string, lossless bool, budget uint64) (*EPUBZipStorageImageWriter, error) {
 	fh, err := os.Create(filename)
 	if err != nil {
 		return nil, err
 	}
 	fz := zip.NewWriter(fh)
-	return &EPUBZipStorageImageWriter{fh, fz, format, &sync.Mutex{}}, nil
+	return &EPUBZipStorageImageWriter{fh, fz, format, lossless, budget, &sync.Mutex{}}, nil
 }
 
 func (e *EPUBZipStorageImageWriter) Close() error {
@@ -32,11 +35,23 @@ func (e *EPUBZipStorageImageWriter) Close() error {
 }
 
 func (e *EPUBZipStorageImageWriter) Add(filename string, img image.Image, quality int) error {
-	zipImage, err := CompressImage(filename, e.format, img, quality)
+	zipImage, err := CompressImageBudget(filename, e.format, img, quality, e.lossless, e.budget)
 	if err != nil {
 		return err
 	}
+	return e.write(zipImage)
+}
+
+// add an image already encoded
+func (e *EPUBZipStorageImageWriter) AddRaw(filename string, data []byte) error {
+	zipImage, err := CompressRaw(filename, data)
+	if err != nil {
+		return err
+	}
+	return e.write(zipImage)
+}
 
+func (e *EPUBZipStorageImageWriter) write(zipImage *ZipImage) error {
 	e.mut.Lock()
 	defer e.mut.Unlock()
 	fh, err := e.fz.CreateRaw(zipImage.Header)
@@ -0,0 +1,40 @@
+package epubzip
+
+import (
+	"archive/zip"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestZip64Entries(t *testing.T) {
+	// just above the number of entries of a zip without zip64
+	path := filepath.Join(t.TempDir(), "book.epub")
+	wz, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wz.WriteMagic(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxEntriesWithoutZip64; i++ {
+		if err := wz.WriteContent(fmt.Sprintf("OEBPS/Text/%d.xhtml", i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if len(r.File) != MaxEntriesWithoutZip64+1 {
+		t.Errorf("%d entries, want %d", len(r.File), MaxEntriesWithoutZip64+1)
+	}
+	if r.File[0].Name != "mimetype" || r.File[0].Method != zip.Store {
+		t.Errorf("first entry %s, method %d", r.File[0].Name, r.File[0].Method)
+	}
+}
@@ -0,0 +1,409 @@
+/*
+iccprofile convert to sRGB the images with an embedded color profile (ICC).
+
+A scan can embed the profile of its color space, like Adobe RGB, Display P3 or a gray with its own gamma.
+The decoders of the standard library ignore it and read the pixels as sRGB, so the colors and the levels are off.
+
+Only the matrix/TRC profiles are supported, RGB and gray: they are the ones embedded by the scanners and
+the image editors. The other profiles (lookup tables, CMYK) are ignored, the pixels are read as sRGB.
+*/
+package iccprofile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+)
+
+var ErrUnsupported = errors.New("iccprofile: unsupported profile")
+
+// profile of an RGB or a gray color space
+type Profile struct {
+	gray bool
+	// the pixel values 0-255 of each channel converted to linear light
+	linear [3][256]float64
+	// linear RGB of the profile to linear sRGB
+	matrix [3][3]float64
+}
+
+// the XYZ of the D50 of the profiles to the linear sRGB, the inverse of the sRGB primaries adapted to D50
+var xyzSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// the profile embedded in a jpeg, a png or a webp, nil if none
+func Extract(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return extractJpeg(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return extractPng(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return extractWebp(data)
+	}
+	return nil
+}
+
+// the profile is split into the APP2 segments, numbered from 1
+func extractJpeg(data []byte) []byte {
+	marker := []byte("ICC_PROFILE\x00")
+	chunks := map[int][]byte{}
+	count := 0
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		m := data[i+1]
+		// fill byte before a marker
+		if m == 0xFF {
+			i++
+			continue
+		}
+		// standalone markers
+		if m == 0x01 || (m >= 0xD0 && m <= 0xD7) {
+			i += 2
+			continue
+		}
+		// the scan starts, no more header
+		if m == 0xDA || m == 0xD9 {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+size]
+		if m == 0xE2 && bytes.HasPrefix(segment, marker) && len(segment) > len(marker)+2 {
+			seq, n := int(segment[len(marker)]), int(segment[len(marker)+1])
+			chunks[seq] = segment[len(marker)+2:]
+			count = n
+		}
+		i += 2 + size
+	}
+	if count == 0 || len(chunks) != count {
+		return nil
+	}
+	var profile []byte
+	for seq := 1; seq <= count; seq++ {
+		chunk, ok := chunks[seq]
+		if !ok {
+			return nil
+		}
+		profile = append(profile, chunk...)
+	}
+	return profile
+}
+
+// the iCCP chunk, a name then the compressed profile, before the image data
+func extractPng(data []byte) []byte {
+	for i := 8; i+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		kind := string(data[i+4 : i+8])
+		if size < 0 || i+12+size > len(data) || kind == "IDAT" {
+			return nil
+		}
+		if kind == "iCCP" {
+			chunk := data[i+8 : i+8+size]
+			name := bytes.IndexByte(chunk, 0)
+			if name < 0 || name+2 > len(chunk) || chunk[name+1] != 0 {
+				return nil
+			}
+			r, err := zlib.NewReader(bytes.NewReader(chunk[name+2:]))
+			if err != nil {
+				return nil
+			}
+			defer r.Close()
+			profile, err := io.ReadAll(r)
+			if err != nil {
+				return nil
+			}
+			return profile
+		}
+		i += 12 + size
+	}
+	return nil
+}
+
+// the ICCP chunk of an extended webp
+func extractWebp(data []byte) []byte {
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if size < 0 || i+8+size > len(data) {
+			return nil
+		}
+		if string(data[i:i+4]) == "ICCP" {
+			return data[i+8 : i+8+size]
+		}
+		i += 8 + size + size%2
+	}
+	return nil
+}
+
+// read a matrix/TRC profile, RGB or gray
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, errors.New("iccprofile: invalid profile")
+	}
+	tags := map[string][]byte{}
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count && 132+12*(i+1) <= len(data); i++ {
+		entry := data[132+12*i:]
+		offset, size := int(binary.BigEndian.Uint32(entry[4:])), int(binary.BigEndian.Uint32(entry[8:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return nil, errors.New("iccprofile: invalid tag")
+		}
+		tags[string(entry[0:4])] = data[offset : offset+size]
+	}
+
+	p := &Profile{}
+	switch string(data[16:20]) {
+	case "GRAY":
+		curve, err := parseCurve(tags["kTRC"])
+		if err != nil {
+			return nil, err
+		}
+		p.gray = true
+		p.linear[0] = curve
+	case "RGB ":
+		var m [3][3]float64
+		for c, name := range []string{"r", "g", "b"} {
+			curve, err := parseCurve(tags[name+"TRC"])
+			if err != nil {
+				return nil, err
+			}
+			p.linear[c] = curve
+			xyz, err := parseXYZ(tags[name+"XYZ"])
+			if err != nil {
+				return nil, err
+			}
+			for i := range xyz {
+				m[i][c] = xyz[i]
+			}
+		}
+		p.matrix = multiply(xyzSRGB, m)
+	default:
+		return nil, ErrUnsupported
+	}
+	return p, nil
+}
+
+// a fixed number 16.16
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func parseXYZ(tag []byte) (xyz [3]float64, err error) {
+	if len(tag) < 20 || string(tag[0:4]) != "XYZ " {
+		return xyz, ErrUnsupported
+	}
+	for i := range xyz {
+		xyz[i] = s15Fixed16(tag[8+4*i:])
+	}
+	return xyz, nil
+}
+
+// the tone curve of a channel, a gamma, a table or a parametric curve
+func parseCurve(tag []byte) (curve [256]float64, err error) {
+	if len(tag) < 12 {
+		return curve, ErrUnsupported
+	}
+	var f func(x float64) float64
+	switch string(tag[0:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		switch {
+		case n == 0:
+			f = func(x float64) float64 { return x }
+		case n == 1 && len(tag) >= 14:
+			g := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			f = func(x float64) float64 { return math.Pow(x, g) }
+		case n > 1 && len(tag) >= 12+2*n:
+			table := tag[12:]
+			f = func(x float64) float64 {
+				pos := x * float64(n-1)
+				i := int(pos)
+				if i >= n-1 {
+					return float64(binary.BigEndian.Uint16(table[2*(n-1):])) / 65535
+				}
+				a, b := float64(binary.BigEndian.Uint16(table[2*i:])), float64(binary.BigEndian.Uint16(table[2*i+2:]))
+				return (a + (b-a)*(pos-float64(i))) / 65535
+			}
+		default:
+			return curve, ErrUnsupported
+		}
+	case "para":
+		var params [7]float64
+		kind := int(binary.BigEndian.Uint16(tag[8:]))
+		n := []int{1, 3, 4, 5, 7}
+		if kind >= len(n) || len(tag) < 12+4*n[kind] {
+			return curve, ErrUnsupported
+		}
+		for i := 0; i < n[kind]; i++ {
+			params[i] = s15Fixed16(tag[12+4*i:])
+		}
+		g, a, b, c, d, e, ff := params[0], params[1], params[2], params[3], params[4], params[5], params[6]
+		switch kind {
+		case 0:
+			f = func(x float64) float64 { return math.Pow(x, g) }
+		case 1:
+			f = func(x float64) float64 {
+				if x >= -b/a {
+					return math.Pow(a*x+b, g)
+				}
+				return 0
+			}
+		case 2:
+			f = func(x float64) float64 {
+				if x >= -b/a {
+					return math.Pow(a*x+b, g) + c
+				}
+				return c
+			}
+		case 3:
+			f = func(x float64) float64 {
+				if x >= d {
+					return math.Pow(a*x+b, g)
+				}
+				return c * x
+			}
+		case 4:
+			f = func(x float64) float64 {
+				if x >= d {
+					return math.Pow(a*x+b, g) + e
+				}
+				return c*x + ff
+			}
+		}
+	default:
+		return curve, ErrUnsupported
+	}
+	for v := range curve {
+		curve[v] = math.Max(0, math.Min(1, f(float64(v)/255)))
+	}
+	return curve, nil
+}
+
+func multiply(a, b [3][3]float64) (m [3][3]float64) {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return
+}
+
+// steps of the linear light in the table of the srgb values
+const encodeSteps = 1 << 14
+
+var encodeTable = func() (t [encodeSteps + 1]uint8) {
+	for i := range t {
+		l := float64(i) / encodeSteps
+		if l <= 0.0031308 {
+			l *= 12.92
+		} else {
+			l = 1.055*math.Pow(l, 1/2.4) - 0.055
+		}
+		t[i] = uint8(math.Round(l * 255))
+	}
+	return
+}()
+
+// linear light to a srgb value
+func encode(l float64) uint8 {
+	return encodeTable[int(math.Max(0, math.Min(1, l))*encodeSteps+0.5)]
+}
+
+// the srgb value of each pixel value of a channel, with the identity matrix
+func (p *Profile) table(c int) (t [256]uint8) {
+	for v := range t {
+		t[v] = encode(p.linear[c][v])
+	}
+	return
+}
+
+// the profile is sRGB, or close enough to not change any pixel by more than a level
+func (p *Profile) IsSRGB() bool {
+	channels := 3
+	if p.gray {
+		channels = 1
+	} else {
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				identity := 0.0
+				if i == j {
+					identity = 1
+				}
+				if math.Abs(p.matrix[i][j]-identity) > 0.002 {
+					return false
+				}
+			}
+		}
+	}
+	for c := 0; c < channels; c++ {
+		for v, s := range p.table(c) {
+			if d := int(s) - v; d < -1 || d > 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the image with its pixels converted to sRGB, a gray image stays gray
+func (p *Profile) Convert(img image.Image) image.Image {
+	if p.gray {
+		t := p.table(0)
+		if src, ok := img.(*image.Gray); ok {
+			b := src.Bounds()
+			dst := image.NewGray(b)
+			for y := 0; y < b.Dy(); y++ {
+				row := src.Pix[y*src.Stride : y*src.Stride+b.Dx()]
+				for x, v := range row {
+					dst.Pix[y*dst.Stride+x] = t[v]
+				}
+			}
+			return dst
+		}
+		dst := image.NewNRGBA(img.Bounds())
+		draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+		for i := 0; i < len(dst.Pix); i += 4 {
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = t[dst.Pix[i]], t[dst.Pix[i+1]], t[dst.Pix[i+2]]
+		}
+		return dst
+	}
+
+	dst := image.NewNRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+	m := p.matrix
+	for i := 0; i < len(dst.Pix); i += 4 {
+		r, g, b := p.linear[0][dst.Pix[i]], p.linear[1][dst.Pix[i+1]], p.linear[2][dst.Pix[i+2]]
+		dst.Pix[i] = encode(m[0][0]*r + m[0][1]*g + m[0][2]*b)
+		dst.Pix[i+1] = encode(m[1][0]*r + m[1][1]*g + m[1][2]*b)
+		dst.Pix[i+2] = encode(m[2][0]*r + m[2][1]*g + m[2][2]*b)
+	}
+	return dst
+}
+
+// the image decoded from data converted to sRGB with its embedded profile.
+//
+// the image is returned as is without profile, with an sRGB or an unsupported one.
+func ToSRGB(img image.Image, data []byte) image.Image {
+	profile := Extract(data)
+	if profile == nil {
+		return img
+	}
+	p, err := Parse(profile)
+	if err != nil || p.IsSRGB() {
+		return img
+	}
+	return p.Convert(img)
+}
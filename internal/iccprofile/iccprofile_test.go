@@ -0,0 +1,236 @@
+package iccprofile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// primaries of sRGB and Adobe RGB (1998) adapted to D50: the XYZ of the red, the green and the blue
+var (
+	srgbPrimaries  = [3][3]float64{{0.4360747, 0.2225045, 0.0139322}, {0.3850649, 0.7168786, 0.0971045}, {0.1430804, 0.0606169, 0.7141733}}
+	adobePrimaries = [3][3]float64{{0.6097559, 0.3111242, 0.0194647}, {0.2052401, 0.6256560, 0.0608902}, {0.1492240, 0.0632197, 0.7448387}}
+)
+
+func fixed(v float64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(v*65536))))
+}
+
+func xyzTag(xyz [3]float64) []byte {
+	tag := append([]byte("XYZ "), 0, 0, 0, 0)
+	for _, v := range xyz {
+		tag = append(tag, fixed(v)...)
+	}
+	return tag
+}
+
+// a curve of one gamma
+func gammaTag(g float64) []byte {
+	tag := append([]byte("curv"), 0, 0, 0, 0, 0, 0, 0, 1)
+	return binary.BigEndian.AppendUint16(tag, uint16(math.Round(g*256)))
+}
+
+// the parametric curve of sRGB
+func srgbCurveTag() []byte {
+	tag := append([]byte("para"), 0, 0, 0, 0, 0, 3, 0, 0)
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		tag = append(tag, fixed(v)...)
+	}
+	return tag
+}
+
+// a profile with these tags, in the order of the names
+func buildProfile(space string, names []string, tags map[string][]byte) []byte {
+	header := make([]byte, 128)
+	copy(header[12:], "mntr")
+	copy(header[16:], space)
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(names)))
+	var data []byte
+	offset := 128 + 4 + 12*len(names)
+	for _, name := range names {
+		tag := tags[name]
+		table = append(table, name...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(data)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag)))
+		data = append(data, tag...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func rgbProfile(primaries [3][3]float64, curve []byte) []byte {
+	return buildProfile("RGB ", []string{"rXYZ", "gXYZ", "bXYZ", "rTRC", "gTRC", "bTRC"}, map[string][]byte{
+		"rXYZ": xyzTag(primaries[0]), "gXYZ": xyzTag(primaries[1]), "bXYZ": xyzTag(primaries[2]),
+		"rTRC": curve, "gTRC": curve, "bTRC": curve,
+	})
+}
+
+// a jpeg with the profile in 2 APP2 segments after the SOI
+func jpegWithProfile(t *testing.T, img image.Image, profile []byte) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	half := len(profile) / 2
+	for i, chunk := range [][]byte{profile[:half], profile[half:]} {
+		segment := append([]byte("ICC_PROFILE\x00"), byte(i+1), 2)
+		segment = append(segment, chunk...)
+		out = append(out, 0xFF, 0xE2)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+// a png with the profile in an iCCP chunk after the IHDR
+func pngWithProfile(t *testing.T, img image.Image, profile []byte) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write(profile)
+	w.Close()
+	chunk := append([]byte("icc\x00\x00"), compressed.Bytes()...)
+
+	ihdr := 8 + 12 + int(binary.BigEndian.Uint32(data[8:]))
+	out := append([]byte{}, data[:ihdr]...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(chunk)))
+	out = append(out, "iCCP"...)
+	out = append(out, chunk...)
+	out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte("iCCP"), chunk...)))
+	return append(out, data[ihdr:]...)
+}
+
+func TestExtract(t *testing.T) {
+	profile := rgbProfile(adobePrimaries, gammaTag(2.2))
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	if got := Extract(jpegWithProfile(t, img, profile)); !bytes.Equal(got, profile) {
+		t.Errorf("jpeg: profile of %d bytes, want %d", len(got), len(profile))
+	}
+	if got := Extract(pngWithProfile(t, img, profile)); !bytes.Equal(got, profile) {
+		t.Errorf("png: profile of %d bytes, want %d", len(got), len(profile))
+	}
+
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	if got := Extract(buf.Bytes()); got != nil {
+		t.Errorf("jpeg without profile: profile of %d bytes", len(got))
+	}
+}
+
+func TestIsSRGB(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		profile []byte
+		want    bool
+	}{
+		{"sRGB", rgbProfile(srgbPrimaries, srgbCurveTag()), true},
+		{"Adobe RGB", rgbProfile(adobePrimaries, gammaTag(563.0/256)), false},
+		{"sRGB primaries, gamma 1.8", rgbProfile(srgbPrimaries, gammaTag(1.8)), false},
+	} {
+		p, err := Parse(c.profile)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if got := p.IsSRGB(); got != c.want {
+			t.Errorf("%s: IsSRGB = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// the srgb value of a linear light, computed without table
+func srgbValue(l float64) float64 {
+	l = math.Max(0, math.Min(1, l))
+	if l <= 0.0031308 {
+		return 255 * 12.92 * l
+	}
+	return 255 * (1.055*math.Pow(l, 1/2.4) - 0.055)
+}
+
+func TestWideGamut(t *testing.T) {
+	// a muted orange of an Adobe RGB scan
+	src := color.NRGBA{200, 120, 60, 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = src.R, src.G, src.B, src.A
+	}
+	g := 563.0 / 256
+	data := pngWithProfile(t, img, rgbProfile(adobePrimaries, gammaTag(g)))
+
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := color.NRGBAModel.Convert(ToSRGB(decoded, data).At(1, 1)).(color.NRGBA)
+
+	// Adobe RGB to XYZ D50 to sRGB
+	linear := [3]float64{math.Pow(200.0/255, g), math.Pow(120.0/255, g), math.Pow(60.0/255, g)}
+	var xyz [3]float64
+	for c := range linear {
+		for i := range xyz {
+			xyz[i] += adobePrimaries[c][i] * linear[c]
+		}
+	}
+	want := [3]float64{}
+	for i := range want {
+		want[i] = srgbValue(xyzSRGB[i][0]*xyz[0] + xyzSRGB[i][1]*xyz[1] + xyzSRGB[i][2]*xyz[2])
+	}
+	for i, v := range []uint8{got.R, got.G, got.B} {
+		if math.Abs(float64(v)-want[i]) > 1.5 {
+			t.Errorf("channel %d = %d, want %.1f", i, v, want[i])
+		}
+	}
+	// the red is more saturated in sRGB
+	if got.R <= src.R || got.B >= src.B {
+		t.Errorf("converted to %v, want a more saturated orange than %v", got, src)
+	}
+}
+
+func TestGrayProfile(t *testing.T) {
+	// a linear gray, the middle of the light is brighter in sRGB
+	profile := buildProfile("GRAY", []string{"kTRC"}, map[string][]byte{"kTRC": gammaTag(1)})
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	data := jpegWithProfile(t, img, profile)
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := ToSRGB(decoded, data).(*image.Gray)
+	if !ok {
+		t.Fatal("gray image not kept gray")
+	}
+	if want := srgbValue(128.0 / 255); math.Abs(float64(out.Pix[0])-want) > 1.5 {
+		t.Errorf("gray = %d, want %.1f", out.Pix[0], want)
+	}
+}
+
+func TestUnsupportedProfile(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	data := jpegWithProfile(t, img, buildProfile("CMYK", nil, nil))
+	if got := ToSRGB(img, data); got != image.Image(img) {
+		t.Error("image converted with an unsupported profile")
+	}
+}
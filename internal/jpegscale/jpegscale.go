@@ -0,0 +1,26 @@
+/*
+jpegscale decode a jpeg at a reduced size (1/2, 1/4 or 1/8).
+
+The jpeg format allows to decode the image directly at a smaller scale, without computing the full resolution.
+It is much faster and uses less memory for a big scan reduced to a small device.
+
+The standard library doesn't support it, so it relies on libjpeg (or libjpeg-turbo) with cgo.
+It is only available when built with the tag libjpeg:
+
+	go build -tags libjpeg
+
+Otherwise Enabled is false and Decode always returns ErrNotEnabled.
+*/
+package jpegscale
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrNotEnabled = errors.New("jpegscale: built without libjpeg")
+
+// detect a jpeg from its magic number
+func IsJpeg(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF})
+}
@@ -0,0 +1,160 @@
+//go:build libjpeg && cgo
+
+package jpegscale
+
+/*
+#cgo LDFLAGS: -ljpeg
+#include <stdio.h>
+#include <stdlib.h>
+#include <setjmp.h>
+#include <jpeglib.h>
+
+struct gcc_error {
+	struct jpeg_error_mgr pub;
+	jmp_buf jmp;
+	char msg[JMSG_LENGTH_MAX];
+};
+
+// libjpeg exits the process on error by default
+static void gcc_error_exit(j_common_ptr cinfo) {
+	struct gcc_error *err = (struct gcc_error *)cinfo->err;
+	(*cinfo->err->format_message)(cinfo, err->msg);
+	longjmp(err->jmp, 1);
+}
+
+static void gcc_output_message(j_common_ptr cinfo) {
+}
+
+// size of the image, -1 on error
+static int gcc_jpeg_size(unsigned char *data, unsigned long size, int *width, int *height, char *msg) {
+	struct jpeg_decompress_struct cinfo;
+	struct gcc_error err;
+
+	cinfo.err = jpeg_std_error(&err.pub);
+	err.pub.error_exit = gcc_error_exit;
+	err.pub.output_message = gcc_output_message;
+	if (setjmp(err.jmp)) {
+		snprintf(msg, JMSG_LENGTH_MAX, "%s", err.msg);
+		jpeg_destroy_decompress(&cinfo);
+		return -1;
+	}
+
+	jpeg_create_decompress(&cinfo);
+	jpeg_mem_src(&cinfo, data, size);
+	jpeg_read_header(&cinfo, TRUE);
+	*width = cinfo.image_width;
+	*height = cinfo.image_height;
+	jpeg_destroy_decompress(&cinfo);
+	return 0;
+}
+
+// decode the image reduced by 1/denom into a buffer to free, gray or rgb. NULL on error.
+static unsigned char *gcc_jpeg_decode(unsigned char *data, unsigned long size, int denom, int *width, int *height, int *components, char *msg) {
+	struct jpeg_decompress_struct cinfo;
+	struct gcc_error err;
+	unsigned char *volatile out = NULL;
+
+	cinfo.err = jpeg_std_error(&err.pub);
+	err.pub.error_exit = gcc_error_exit;
+	err.pub.output_message = gcc_output_message;
+	if (setjmp(err.jmp)) {
+		snprintf(msg, JMSG_LENGTH_MAX, "%s", err.msg);
+		jpeg_destroy_decompress(&cinfo);
+		free(out);
+		return NULL;
+	}
+
+	jpeg_create_decompress(&cinfo);
+	jpeg_mem_src(&cinfo, data, size);
+	jpeg_read_header(&cinfo, TRUE);
+
+	switch (cinfo.jpeg_color_space) {
+	case JCS_GRAYSCALE:
+		cinfo.out_color_space = JCS_GRAYSCALE;
+		break;
+	case JCS_CMYK:
+	case JCS_YCCK:
+		snprintf(msg, JMSG_LENGTH_MAX, "cmyk is not supported");
+		jpeg_destroy_decompress(&cinfo);
+		return NULL;
+	default:
+		cinfo.out_color_space = JCS_RGB;
+	}
+	cinfo.scale_num = 1;
+	cinfo.scale_denom = denom;
+
+	jpeg_start_decompress(&cinfo);
+	*width = cinfo.output_width;
+	*height = cinfo.output_height;
+	*components = cinfo.output_components;
+
+	size_t stride = (size_t)cinfo.output_width * cinfo.output_components;
+	out = malloc(stride * cinfo.output_height);
+	if (out == NULL) {
+		snprintf(msg, JMSG_LENGTH_MAX, "out of memory");
+		jpeg_destroy_decompress(&cinfo);
+		return NULL;
+	}
+	while (cinfo.output_scanline < cinfo.output_height) {
+		JSAMPROW row = out + stride * cinfo.output_scanline;
+		jpeg_read_scanlines(&cinfo, &row, 1);
+	}
+
+	jpeg_finish_decompress(&cinfo);
+	jpeg_destroy_decompress(&cinfo);
+	return out;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+const Enabled = true
+
+// Decode the jpeg reduced by the factor returned by scale for its size: 1, 2, 4 or 8.
+func Decode(data []byte, scale func(width, height int) int) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, errors.New("jpegscale: empty image")
+	}
+
+	msg := (*C.char)(C.malloc(C.JMSG_LENGTH_MAX))
+	defer C.free(unsafe.Pointer(msg))
+
+	src := (*C.uchar)(C.CBytes(data))
+	defer C.free(unsafe.Pointer(src))
+
+	var width, height, components C.int
+	if C.gcc_jpeg_size(src, C.ulong(len(data)), &width, &height, msg) != 0 {
+		return nil, fmt.Errorf("jpegscale: %s", C.GoString(msg))
+	}
+
+	denom := scale(int(width), int(height))
+	switch denom {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("jpegscale: invalid scale 1/%d", denom)
+	}
+
+	out := C.gcc_jpeg_decode(src, C.ulong(len(data)), C.int(denom), &width, &height, &components, msg)
+	if out == nil {
+		return nil, fmt.Errorf("jpegscale: %s", C.GoString(msg))
+	}
+	defer C.free(unsafe.Pointer(out))
+
+	w, h := int(width), int(height)
+	pix := C.GoBytes(unsafe.Pointer(out), C.int(w*h*int(components)))
+	if components == 1 {
+		return &image.Gray{Pix: pix, Stride: w, Rect: image.Rect(0, 0, w, h)}, nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, j := 0, 0; i < len(pix); i, j = i+3, j+4 {
+		img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3] = pix[i], pix[i+1], pix[i+2], 0xFF
+	}
+	return img, nil
+}
@@ -0,0 +1,12 @@
+//go:build !libjpeg || !cgo
+
+package jpegscale
+
+import "image"
+
+const Enabled = false
+
+// Decode is not available without libjpeg.
+func Decode(data []byte, scale func(width, height int) int) (image.Image, error) {
+	return nil, ErrNotEnabled
+}
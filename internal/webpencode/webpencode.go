@@ -0,0 +1,16 @@
+/*
+webpencode encode an image as webp, lossy or lossless.
+
+The standard library and golang.org/x/image only decode the webp, so the encoder relies on libwebp with cgo.
+The sources of libwebp are bundled with github.com/chai2010/webp, only a C compiler is needed.
+It is only available when built with the tag webp:
+
+	go build -tags webp
+
+Otherwise Enabled is false and Encode always returns ErrNotEnabled.
+*/
+package webpencode
+
+import "errors"
+
+var ErrNotEnabled = errors.New("webpencode: built without webp")
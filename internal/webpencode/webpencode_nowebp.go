@@ -0,0 +1,15 @@
+//go:build !webp || !cgo
+
+package webpencode
+
+import (
+	"image"
+	"io"
+)
+
+const Enabled = false
+
+// Encode is not available without webp.
+func Encode(w io.Writer, img image.Image, quality int, lossless bool) error {
+	return ErrNotEnabled
+}
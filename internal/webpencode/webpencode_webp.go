@@ -0,0 +1,17 @@
+//go:build webp && cgo
+
+package webpencode
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+const Enabled = true
+
+// Encode the image with the quality (1-100), or without loss, the quality is then ignored.
+func Encode(w io.Writer, img image.Image, quality int, lossless bool) error {
+	return webp.Encode(w, img, &webp.Options{Lossless: lossless, Quality: float32(quality)})
+}
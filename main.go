@@ -11,8 +11,10 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"sync/atomic"
 
 	"github.com/celogeek/go-comic-converter/v2/internal/converter"
+	"github.com/celogeek/go-comic-converter/v2/internal/converter/options"
 	"github.com/celogeek/go-comic-converter/v2/internal/epub"
 	epuboptions "github.com/celogeek/go-comic-converter/v2/internal/epub/options"
 	"github.com/tcnksm/go-latest"
@@ -25,6 +27,17 @@ func main() {
 	}
 	cmd.InitParse()
 	cmd.Parse()
+	if err := cmd.LoadProfiles(); err != nil {
+		cmd.Fatal(err)
+	}
+	// the profile of each input of a batch is applied with its embedded settings
+	base := *cmd.Options
+	cmd.ApplyProfile()
+
+	if cmd.Options.ListProfiles {
+		fmt.Println(cmd.Options.ListProfilesByGroup())
+		return
+	}
 
 	if cmd.Options.Version {
 		bi, ok := debug.ReadBuildInfo()
@@ -92,45 +105,190 @@ $ go install github.com/celogeek/go-comic-converter/v%d@%s
 		return
 	}
 
+	if cmd.Options.Batch != "" {
+		inputs, err := cmd.BatchInputs()
+		if err != nil {
+			cmd.Fatal(err)
+		}
+		batch(cmd, base, inputs)
+		return
+	}
+
+	if cmd.Options.Recursive {
+		inputs, err := cmd.RecursiveInputs()
+		if err != nil {
+			cmd.Fatal(err)
+		}
+		if inputs != nil {
+			batch(cmd, base, inputs)
+			return
+		}
+	}
+
 	if err := cmd.Validate(); err != nil {
 		cmd.Fatal(err)
 	}
 
+	if err := convert(cmd); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// convert the inputs one after the other with the options of the command line, exit 1 if any failed
+func batch(cmd *converter.Converter, base options.Options, inputs []string) {
+	errs := make([]error, len(inputs))
+	for i, input := range inputs {
+		fmt.Fprintf(os.Stderr, "\n[%d/%d] %s\n", i+1, len(inputs), input)
+		cmd.StartInput(base, input)
+		if errs[i] = cmd.Validate(); errs[i] == nil {
+			errs[i] = convert(cmd)
+		}
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", errs[i])
+		}
+	}
+	cmd.BatchReport(inputs, errs)
+	for _, err := range errs {
+		if err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// print the progress every 10% when the progress bar can't be rendered:
+// stderr is redirected to a log or read by a script.
+func progressLine(quiet bool) func(done, total int) {
+	if fi, err := os.Stderr.Stat(); quiet || err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+		return nil
+	}
+	var last int64
+	return func(done, total int) {
+		pct := int64(done * 100 / total / 10 * 10)
+		for {
+			l := atomic.LoadInt64(&last)
+			if pct <= l {
+				return
+			}
+			if atomic.CompareAndSwapInt64(&last, l, pct) {
+				fmt.Fprintf(os.Stderr, "Processing %d%% (%d/%d)\n", pct, done, total)
+				return
+			}
+		}
+	}
+}
+
+// convert the input of the validated options to an epub
+func convert(cmd *converter.Converter) error {
 	fmt.Fprintln(os.Stderr, cmd.Options)
 
 	profile := cmd.Options.GetProfile()
 
-	if err := epub.New(&epuboptions.Options{
-		Input:                      cmd.Options.Input,
-		Output:                     cmd.Options.Output,
+	input, err := cmd.Download()
+	if err != nil {
+		cmd.Clean()
+		return err
+	}
+
+	output := cmd.Options.Output
+	if cmd.IsMobi() && !cmd.Options.Dry {
+		if output, err = cmd.MobiOutput(); err != nil {
+			cmd.Clean()
+			return err
+		}
+	}
+
+	e := epub.New(&epuboptions.Options{
+		Input:                      input,
+		Output:                     output,
 		LimitMb:                    cmd.Options.LimitMb,
+		LimitFiles:                 cmd.Options.LimitFiles,
+		PartFormat:                 cmd.Options.PartFormat,
+		NoZip64:                    cmd.Options.NoZip64,
+		LimitExcludeCover:          cmd.Options.LimitExcludeCover,
 		Title:                      cmd.Options.Title,
+		Identifier:                 cmd.Options.Identifier,
 		TitlePage:                  cmd.Options.TitlePage,
+		VolumeInfoPage:             cmd.Options.VolumeInfoPage,
+		BlankPage:                  cmd.Options.BlankPage,
 		Author:                     cmd.Options.Author,
+		Series:                     cmd.Options.Series,
+		Volume:                     cmd.Options.Volume,
+		Year:                       cmd.Options.Year,
+		OwnerTag:                   cmd.Options.OwnerTag,
+		Language:                   cmd.Options.Language,
+		Publisher:                  cmd.Options.Publisher,
 		StripFirstDirectoryFromToc: cmd.Options.StripFirstDirectoryFromToc,
+		MinChapterPages:            cmd.Options.MinChapterPages,
+		TocThumbnails:              cmd.Options.TocThumbnails,
 		SortPathMode:               cmd.Options.SortPathMode,
+		ReverseOrder:               cmd.Options.ReverseOrder,
+		IncludeHidden:              cmd.Options.IncludeHidden,
+		WarnUnsupported:            cmd.Options.WarnUnsupported,
+		SniffContent:               cmd.Options.SniffContent,
+		NoCoverFile:                cmd.Options.NoCoverFile,
+		CoverSelector:              cmd.Options.Cover,
+		CoverOnly:                  cmd.Options.CoverOnly,
+		RarMode:                    cmd.Options.RarMode,
+		Order:                      cmd.Options.Order,
+		Sample:                     cmd.Options.Sample,
+		SamplePages:                cmd.Options.SamplePages,
+		KeepPages:                  cmd.Options.GetKeepPages(),
+		PdfDpi:                     cmd.Options.PdfDpi,
+		PdfRender:                  cmd.Options.PdfRender,
+		Colophon:                   cmd.Options.Colophon,
+		ArchiveCbz:                 cmd.Options.ArchiveCbz,
 		Workers:                    cmd.Options.Workers,
+		Readers:                    cmd.Options.Readers,
+		Decoders:                   cmd.Options.Decoders,
 		Dry:                        cmd.Options.Dry,
 		DryVerbose:                 cmd.Options.DryVerbose,
+		Preflight:                  cmd.Options.Preflight,
+		CheckArchive:               cmd.Options.CheckArchive,
+		Password:                   cmd.Options.Password,
+		SkipBad:                    cmd.Options.SkipBad,
 		Quiet:                      cmd.Options.Quiet,
+		Target:                     cmd.Options.Target,
+		Panels:                     cmd.Options.Panels,
+		OnProgress:                 progressLine(cmd.Options.Quiet),
 		Image: &epuboptions.Image{
 			Quality:       cmd.Options.Quality,
+			Qualities:     cmd.Options.GetQualities(),
 			GrayScale:     cmd.Options.Grayscale,
 			GrayScaleMode: cmd.Options.GrayscaleMode,
 			Crop: &epuboptions.Crop{
-				Enabled: cmd.Options.Crop,
-				Left:    cmd.Options.CropRatioLeft,
-				Up:      cmd.Options.CropRatioUp,
-				Right:   cmd.Options.CropRatioRight,
-				Bottom:  cmd.Options.CropRatioBottom,
+				Enabled:   cmd.Options.Crop,
+				FromFirst: cmd.Options.CropFromFirst,
+				Left:      cmd.Options.CropRatioLeft,
+				Up:        cmd.Options.CropRatioUp,
+				Right:     cmd.Options.CropRatioRight,
+				Bottom:    cmd.Options.CropRatioBottom,
+				Threshold: cmd.Options.CropThreshold,
+				MinRatio:  cmd.Options.CropMinRatio,
+				Padding:   cmd.Options.CropPadding,
 			},
 			Brightness:          cmd.Options.Brightness,
 			Contrast:            cmd.Options.Contrast,
+			AutoContrast:        cmd.Options.AutoContrast,
+			AutoContrastClip:    cmd.Options.AutoContrastClip,
+			Gamma:               cmd.Options.Gamma,
+			Sharpen:             cmd.Options.Sharpen,
+			SharpenAmount:       cmd.Options.SharpenAmount,
+			Dither:              cmd.Options.Dither,
+			DitherLevels:        cmd.Options.DitherLevels,
 			AutoRotate:          cmd.Options.AutoRotate,
 			AutoSplitDoublePage: cmd.Options.AutoSplitDoublePage,
+			SpreadMode:          cmd.Options.SpreadMode,
+			DoublePageRatio:     cmd.Options.DoublePageRatio,
+			LongStrip:           cmd.Options.LongStrip,
+			LongStripHeight:     cmd.Options.LongStripHeight,
 			NoBlankImage:        cmd.Options.NoBlankImage,
 			Manga:               cmd.Options.Manga,
+			AutoDirection:       cmd.Options.AutoDirection,
 			HasCover:            cmd.Options.HasCover,
+			CoverFit:            cmd.Options.CoverFit,
+			CoverBack:           cmd.Options.CoverBack,
+			CoverRatio:          cmd.Options.GetCoverRatio(),
 			View: &epuboptions.View{
 				Width:        profile.Width,
 				Height:       profile.Height,
@@ -141,14 +299,41 @@ $ go install github.com/celogeek/go-comic-converter/v%d@%s
 					Background: cmd.Options.BackgroundColor,
 				},
 			},
-			Resize: !cmd.Options.NoResize,
-			Format: cmd.Options.Format,
+			Resize:        !cmd.Options.NoResize,
+			BoxRatio:      cmd.Options.BoxRatio,
+			ResizeFilter:  cmd.Options.ResizeFilter,
+			FitMode:       cmd.Options.FitMode,
+			PadColor:      cmd.Options.PadColor,
+			Format:        cmd.Options.Format,
+			Lossless:      cmd.Options.Lossless,
+			LosslessCover: cmd.Options.LosslessCover,
+			BytesPerPage:  cmd.Options.BytesPerPage,
+			RawPages:      cmd.Options.RawPages,
+			Deskew:        cmd.Options.Deskew,
+			Descreen:      cmd.Options.Descreen,
+			ScaledDecode:  cmd.Options.ScaledDecode,
 		},
-	}).Write(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	})
+	err = e.Write()
+	if err == nil && cmd.IsMobi() && !cmd.Options.Dry && !cmd.Options.Preflight {
+		e.Stats.Outputs, err = cmd.Mobi(e.Stats.Outputs)
+	}
+	cmd.Clean()
+	if !cmd.Options.Dry && !cmd.Options.Preflight {
+		if err := cmd.StatsLog(e.Stats.Outputs, e.Stats.Pages, e.Stats.Skipped, err); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+	if err == nil && !cmd.Options.Dry && !cmd.Options.Preflight {
+		if err := cmd.OpdsEntry(e.UID, e.Stats.Outputs, e.Stats.Cover); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+	if err != nil {
+		return err
 	}
-	if !cmd.Options.Dry {
+	if !cmd.Options.Dry && !cmd.Options.Preflight {
 		cmd.Stats()
 	}
+	return nil
 }